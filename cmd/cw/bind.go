@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	pflag "github.com/spf13/pflag"
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/metadata"
+	"github.com/tstromberg/campwiz/pkg/search"
+)
+
+var candidatesFlag *int = pflag.Int("bind_candidates", 5, "bind: number of candidate campgrounds to offer for each result")
+
+const bindHelp = `  <enter>                      accept candidate #1
+  <n>                          accept candidate #n
+  <property id> <campground>   bind to another campground
+  s                            skip this result
+  q                            stop, saving what has been accepted`
+
+// bindCmd implements "cw bind", which learns provider ID bindings for results that are not yet bound
+func bindCmd(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: cw bind [--dates=] [--providers=] [--fetch_policy=cache-only]")
+	}
+
+	cs, err := cache.New(cacheConfig())
+	if err != nil {
+		return err
+	}
+
+	q, err := query()
+	if err != nil {
+		return err
+	}
+
+	srcs, props, err := metadata.LoadAll()
+	if err != nil {
+		return fmt.Errorf("loadall failed: %w", err)
+	}
+
+	idx := search.NewIndex(srcs, props)
+	rs, errs := search.Annotated(*providersFlag, q, cs, idx)
+	for _, err := range errs {
+		fmt.Fprintf(os.Stderr, "search error: %v\n", err)
+	}
+
+	ub := unbound(rs, idx)
+	if len(ub) == 0 {
+		fmt.Printf("all %d results are bound\n", len(rs))
+		return nil
+	}
+
+	fmt.Printf("%d of %d results are unbound:\n%s\n", len(ub), len(rs), bindHelp)
+	bs, err := promptBindings(os.Stdin, os.Stdout, ub, idx, props)
+	if err != nil {
+		return err
+	}
+
+	if len(bs) == 0 {
+		fmt.Println("no bindings accepted")
+		return nil
+	}
+
+	if err := metadata.BindAll(bs); err != nil {
+		return err
+	}
+	fmt.Printf("saved %d bindings\n", len(bs))
+	return nil
+}
+
+// unbound returns results with a provider ID that is not bound to a campground, once per ID
+func unbound(rs []campwiz.Result, idx *search.Index) []campwiz.Result {
+	seen := map[string]bool{}
+	ub := []campwiz.Result{}
+	for _, r := range rs {
+		if r.Provider == "" || strings.TrimSpace(r.ResID) == "" {
+			continue
+		}
+		key := r.Provider + ":" + strings.TrimSpace(r.ResID)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if _, ok := idx.Bound(r); ok {
+			continue
+		}
+		ub = append(ub, r)
+	}
+	return ub
+}
+
+// candidates returns the best matching distinct campgrounds for a result
+func candidates(r campwiz.Result, idx *search.Index, n int) []search.Match {
+	seen := map[*campwiz.Campground]bool{}
+	ms := []search.Match{}
+	for _, m := range search.Explain(r, idx) {
+		if m.Campground == nil || seen[m.Campground] {
+			continue
+		}
+		seen[m.Campground] = true
+		ms = append(ms, m)
+		if len(ms) == n {
+			break
+		}
+	}
+	return ms
+}
+
+// promptBindings asks which campground each unbound result should be bound to
+func promptBindings(in io.Reader, out io.Writer, rs []campwiz.Result, idx *search.Index, props map[string]*campwiz.Property) ([]metadata.Binding, error) {
+	s := bufio.NewScanner(in)
+	bs := []metadata.Binding{}
+
+	for i, r := range rs {
+		cs := candidates(r, idx, *candidatesFlag)
+		fmt.Fprintf(out, "\n[%d/%d] %s %q: %s (%s)\n", i+1, len(rs), r.Provider, strings.TrimSpace(r.ResID), r.Name, r.Locale)
+		for j, m := range cs {
+			fmt.Fprintf(out, "  #%d %s/%s: %s [%s: %s]\n", j+1, m.Campground.PropertyID, m.Campground.ID, m.Campground.Name, search.ScoreName(m.Score), m.Detail)
+		}
+
+		for {
+			fmt.Fprint(out, "> ")
+			if !s.Scan() {
+				return bs, s.Err()
+			}
+
+			cg, done, err := choose(strings.Fields(s.Text()), cs, props)
+			if err != nil {
+				fmt.Fprintf(out, "%v\n%s\n", err, bindHelp)
+				continue
+			}
+			if done {
+				return bs, nil
+			}
+			if cg != nil {
+				bs = append(bs, metadata.Binding{PropertyID: cg.PropertyID, CampgroundID: cg.ID, Provider: r.Provider, ID: r.ResID})
+			}
+			break
+		}
+	}
+	return bs, nil
+}
+
+// choose interprets an answer to the binding prompt, returning nil if the result was skipped
+func choose(fields []string, cs []search.Match, props map[string]*campwiz.Property) (*campwiz.Campground, bool, error) {
+	switch {
+	case len(fields) == 0:
+		if len(cs) == 0 {
+			return nil, false, fmt.Errorf("there are no candidates to accept")
+		}
+		return cs[0].Campground, false, nil
+	case len(fields) == 1 && fields[0] == "s":
+		return nil, false, nil
+	case len(fields) == 1 && fields[0] == "q":
+		return nil, true, nil
+	case len(fields) == 1:
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 1 || n > len(cs) {
+			return nil, false, fmt.Errorf("%q is not a candidate", fields[0])
+		}
+		return cs[n-1].Campground, false, nil
+	case len(fields) == 2:
+		if p := props[fields[0]]; p != nil {
+			for _, c := range p.Campgrounds {
+				if c.ID == fields[1] {
+					return c, false, nil
+				}
+			}
+		}
+		return nil, false, fmt.Errorf("unknown campground: %s/%s", fields[0], fields[1])
+	}
+	return nil, false, fmt.Errorf("unexpected answer: %q", strings.Join(fields, " "))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	pflag "github.com/spf13/pflag"
+	"github.com/tstromberg/campwiz/pkg/cache"
+)
+
+var (
+	hostFlag      *string        = pflag.String("host", "", "cache: only include entries fetched from this host")
+	providerFlag  *string        = pflag.String("provider", "", "cache: only include entries fetched by this provider")
+	statusFlag    *int           = pflag.Int("status", 0, "cache: only include entries with this HTTP status code")
+	olderThanFlag *time.Duration = pflag.Duration("older_than", 0, "cache: only include entries older than this")
+	newerThanFlag *time.Duration = pflag.Duration("newer_than", 0, "cache: only include entries newer than this")
+	failingFlag   *bool          = pflag.Bool("failing", false, "cache: only include undecodable entries or unsuccessful responses")
+	expiredFlag   *bool          = pflag.Bool("expired", false, "cache: only include entries older than --max_cache_age")
+	allFlag       *bool          = pflag.Bool("all", false, "cache: allow purging every entry")
+)
+
+const cacheUsage = "usage: cw cache ls|show <key>|purge|stats [--host=] [--provider=] [--status=] [--older_than=] [--newer_than=] [--failing] [--expired]"
+
+// cacheFilter returns the cache filter described by flags
+func cacheFilter() cache.Filter {
+	f := cache.Filter{
+		Host:       *hostFlag,
+		Provider:   *providerFlag,
+		StatusCode: *statusFlag,
+		OlderThan:  *olderThanFlag,
+		NewerThan:  *newerThanFlag,
+		Failing:    *failingFlag,
+	}
+	if *expiredFlag {
+		f.OlderThan = *maxCacheAgeFlag
+	}
+	return f
+}
+
+// cacheCmd implements the "cw cache" subcommands
+func cacheCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(cacheUsage)
+	}
+
+	cs, err := cache.New(cacheConfig())
+	if err != nil {
+		return err
+	}
+
+	l, ok := cs.(cache.Lister)
+	if !ok {
+		return fmt.Errorf("cache store %T can not be listed", cs)
+	}
+
+	f := cacheFilter()
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	switch args[0] {
+	case "ls":
+		es, err := cache.List(l, f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "AGE\tSTATUS\tPROVIDER\tSIZE\tVERSION\tKEY\tURL")
+		for _, e := range es {
+			status := fmt.Sprintf("%d", e.Response.StatusCode)
+			if e.Err != nil {
+				status = "corrupt"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\tv%d\t%s\t%s\n", e.Age().Round(time.Second), status, e.Response.Provider, e.Size, e.Version, e.Key, e.Response.URL)
+		}
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf(cacheUsage)
+		}
+		e := cache.Inspect(cs, args[1])
+		if e.Err != nil {
+			return e.Err
+		}
+		r := e.Response
+		fmt.Fprintf(w, "URL:\t%s\n", r.URL)
+		fmt.Fprintf(w, "Provider:\t%s\n", r.Provider)
+		fmt.Fprintf(w, "Status:\t%d\n", r.StatusCode)
+		fmt.Fprintf(w, "Stored:\t%s (%s ago)\n", r.MTime.Format(time.RFC3339), e.Age().Round(time.Second))
+		fmt.Fprintf(w, "Size:\t%d bytes (%d body)\n", e.Size, len(r.Body))
+		fmt.Fprintf(w, "Format:\tv%d (compressed: %v)\n", e.Version, e.Compressed)
+		for _, c := range r.Cookies {
+			fmt.Fprintf(w, "Cookie:\t%s\n", c)
+		}
+		for k, v := range r.Header {
+			fmt.Fprintf(w, "%s:\t%s\n", k, v)
+		}
+		w.Flush()
+		fmt.Printf("\n%s\n", r.Body)
+	case "purge":
+		if f == (cache.Filter{}) && !*allFlag {
+			return fmt.Errorf("refusing to purge every entry without --all")
+		}
+		es, err := cache.Purge(l, f)
+		if err != nil {
+			return err
+		}
+		s := cache.Summarize(es)
+		fmt.Fprintf(w, "purged %d entries (%d bytes)\n", s.Entries, s.Bytes)
+	case "stats":
+		es, err := cache.List(l, f)
+		if err != nil {
+			return err
+		}
+		s := cache.Summarize(es)
+		fmt.Fprintf(w, "Entries:\t%d\n", s.Entries)
+		fmt.Fprintf(w, "Size:\t%d bytes\n", s.Bytes)
+		fmt.Fprintf(w, "Failing:\t%d\n", s.Failing)
+		fmt.Fprintf(w, "Legacy format:\t%d\n", s.Legacy)
+		if !s.Oldest.IsZero() {
+			fmt.Fprintf(w, "Oldest:\t%s\n", s.Oldest.Format(time.RFC3339))
+			fmt.Fprintf(w, "Newest:\t%s\n", s.Newest.Format(time.RFC3339))
+		}
+		for _, k := range sortedKeys(s.ByProvider) {
+			fmt.Fprintf(w, "Provider %q:\t%d\n", k, s.ByProvider[k])
+		}
+		for _, k := range sortedKeys(s.ByHost) {
+			fmt.Fprintf(w, "Host %q:\t%d\n", k, s.ByHost[k])
+		}
+		codes := []int{}
+		for k := range s.ByStatus {
+			codes = append(codes, k)
+		}
+		sort.Ints(codes)
+		for _, k := range codes {
+			fmt.Fprintf(w, "Status %d:\t%d\n", k, s.ByStatus[k])
+		}
+	default:
+		return fmt.Errorf(cacheUsage)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -6,15 +6,21 @@ import (
 	goflag "flag"
 	"fmt"
 	"os"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/mgutz/ansi"
 	pflag "github.com/spf13/pflag"
+	"github.com/tstromberg/campwiz/pkg/backend"
 	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/drive"
+	"github.com/tstromberg/campwiz/pkg/geo"
 	"github.com/tstromberg/campwiz/pkg/mangle"
 	"github.com/tstromberg/campwiz/pkg/metadata"
+	"github.com/tstromberg/campwiz/pkg/parse"
+	"github.com/tstromberg/campwiz/pkg/rank"
 	"github.com/tstromberg/campwiz/pkg/search"
 	"k8s.io/klog/v2"
 )
@@ -23,19 +29,33 @@ var (
 	datesFlag       *[]string      = pflag.StringSlice("dates", []string{"2021-03-05"}, "dates to search for")
 	milesFlag       *int           = pflag.Int("max_distance", 200, "distance to search within")
 	nightsFlag      *int           = pflag.Int("nights", 2, "number of nights to stay")
-	minRatingFlag   *float64       = pflag.Float64("min_rating", 0, "minimum scenery rating for inclusion")
-	keywordsFlag    *[]string      = pflag.StringSlice("keywords", nil, "keywords to search for")
+	minRatingFlag   *float64       = pflag.Float64("min_rating", 0, "minimum rating for inclusion, on a scale of 0-10")
+	keywordsFlag    *[]string      = pflag.StringSlice("keywords", nil, "full-text query to match campgrounds against, for example: lake \"redwood grove\" -rv")
 	maxCacheAgeFlag *time.Duration = pflag.Duration("max_cache_age", cache.RecommendedMaxAge, "max age of cache")
+	persistFlag     *string        = pflag.String("persist_backend", "", "cache persistence backend (disk, sqlite, mysql, cloudsql), as used by the server")
+	persistPathFlag *string        = pflag.String("persist_path", "", "where the cache is persisted to: directory, file, or DSN (automatic)")
 	latFlag         *float64       = pflag.Float64("lat", 37.4092297, "latitude to search from")
 	lonFlag         *float64       = pflag.Float64("lon", -122.07237049999999, "longitude to search from")
+	originsFlag     *[]string      = pflag.StringArray("origin", nil, "where each party of a group trip travels from, replacing --lat and --lon. Repeatable, for example: --origin \"San Francisco=37.77,-122.42\" --origin Sacramento=38.58,-121.49")
+	originDistFlag  *string        = pflag.String("origin_distance", campwiz.DistanceMax, "how --max_distance applies to multiple origins: max (every party) or sum (all parties combined)")
+	routeFlag       *string        = pflag.String("route", "", "waypoints to search along instead of around a location, for example: 37.77,-122.42;37.88,-119.35;37.36,-118.40")
+	gpxFlag         *string        = pflag.String("gpx", "", "path to a GPX file of a track or route to search along")
+	maxDetourFlag   *int           = pflag.Int("max_detour", 20, "distance from the --route or --gpx path to search within")
+	routerFlag      *string        = pflag.String("router", "heuristic", "how to estimate drive times: heuristic (from distance), osrm (falling back to heuristic), or none")
+	osrmURLFlag     *string        = pflag.String("osrm_url", "https://router.project-osrm.org", "base URL of an OSRM-compatible server, for --router=osrm")
+	maxDriveFlag    *time.Duration = pflag.Duration("max_drive_time", 0, "longest drive time to include, for example: 2h30m (0 for any)")
 	providersFlag   *[]string      = pflag.StringSlice("providers", search.DefaultProviders, "site providers to include")
+	sortFlag        *string        = pflag.String("sort", rank.Default, "order results by: "+strings.Join(rank.Names(), ", "))
+	sortWeightsFlag *string        = pflag.String("sort_weights", "", "signal weights for --sort=composite, for example: rating=3,distance=2,availability=1,awards=1,spots=0.5")
+	harFlag         *string        = pflag.String("har", "", "path to record upstream requests to as a HAR file")
+	policyFlag      *string        = pflag.String("fetch_policy", "normal", "normal, cache-only (never use the network), or refresh (ignore the cache)")
 
 	outTmpl = `
 {{ $srcs := .Sources }}
 {{ range $i, $r := .Results}}
-{{ Color "(" "yellow+d" }}{{ printf "#%d" $i | yellow }}{{ Color ")" "yellow+d" }} {{ Color $r.Name "green+h" }} {{ Color "(" "black+h" }}{{ printf "%.0fmi" $r.Distance | green }}{{ with $r.Locale }}{{ Color "," "black+h"}} {{ . | green }}{{ end }}{{ Color ")" "black+h" }}
+{{ Color "(" "yellow+d" }}{{ printf "#%d" $i | yellow }}{{ Color ")" "yellow+d" }} {{ Color $r.Name "green+h" }} {{ Color "(" "black+h" }}{{ printf "%.0fmi" $r.Distance | green }}{{ with $r.Locale }}{{ Color "," "black+h"}} {{ . | green }}{{ end }}{{ Color ")" "black+h" }}{{ if $.Query.Route }} {{ printf "%.0fmi along the route" $r.RouteMiles | grey }}{{ end }}{{ with drive $r.DriveTime }} {{ . | green }}{{ end }}{{ range $i, $d := $r.Distances }}{{ with index $.Query.Origins $i }} {{ .Name | grey }} {{ printf "%.0fmi" $d | green }}{{ end }}{{ if $r.DriveTimes }}{{ with drive (index $r.DriveTimes $i) }} {{ . | green }}{{ end }}{{ end }}{{ end }}{{ if $r.RatingSources }} {{ $r.RatingSummary | hwhite }}{{ end }}
 {{- range $r.Availability}}
-{{ Color "  >" "cyan" }} {{ printf "%s %d"  .Date.Month .Date.Day | hwhite }}{{ Color ":" "cyan" }} {{.SpotCount}}x{{.Kind}} - {{.URL | cyan }}
+{{ Color "  >" "cyan" }} {{ printf "%s %d"  .Date.Month .Date.Day | hwhite }}{{ Color ":" "cyan" }} {{.SpotCount}}x{{.Kind}} - {{ with .Provider }}{{ . | grey }} {{ end }}{{.URL | cyan }}
 {{- end }}
 {{ with $r.KnownCampground }}
 {{- range $k, $v := .Refs -}}
@@ -59,27 +79,47 @@ type templateContext struct {
 	Errors  []error
 }
 
+// cacheConfig returns the cache configuration described by flags, or the server's environment
+func cacheConfig() cache.Config {
+	c := cache.Config{MaxAge: *maxCacheAgeFlag, Backend: *persistFlag, Path: *persistPathFlag}
+	if c.Backend == "" {
+		c.Backend = os.Getenv("PERSIST_BACKEND")
+	}
+	if c.Path == "" {
+		c.Path = os.Getenv("PERSIST_PATH")
+	}
+	return c
+}
+
 func processFlags() error {
-	cs, err := cache.New(cache.Config{MaxAge: *maxCacheAgeFlag})
+	cs, err := cache.New(cacheConfig())
 	if err != nil {
 		return err
 	}
 
-	q := campwiz.Query{
-		Lon:         *lonFlag,
-		Lat:         *latFlag,
-		StayLength:  *nightsFlag,
-		MaxDistance: *milesFlag,
-		MinRating:   *minRatingFlag,
-		Keywords:    *keywordsFlag,
+	if *harFlag != "" {
+		rec := cache.NewRecorder()
+		cs = cache.Record(cs, rec)
+		defer func() {
+			if err := rec.WriteFile(*harFlag); err != nil {
+				klog.Errorf("unable to write HAR: %v", err)
+				return
+			}
+			klog.Infof("recorded %d requests to %s", rec.Len(), *harFlag)
+		}()
 	}
 
-	for _, ds := range *datesFlag {
-		t, err := time.Parse(dateFormat, ds)
+	q, err := query()
+	if err != nil {
+		return err
+	}
+
+	// Arguments describe the trip in words, such as: tent near Big Sur next 3 weekends no rv
+	if pflag.NArg() > 0 {
+		q, err = parse.Query(strings.Join(pflag.Args(), " "), q, time.Now())
 		if err != nil {
-			klog.Fatalf("unable to parse date %q: %v", ds, err)
+			return fmt.Errorf("query: %w", err)
 		}
-		q.Dates = append(q.Dates, t)
 	}
 
 	srcs, props, err := metadata.LoadAll()
@@ -87,7 +127,12 @@ func processFlags() error {
 		return fmt.Errorf("loadall failed: %w", err)
 	}
 
-	ms, errs := search.Run(*providersFlag, q, cs, props)
+	rt, err := drive.New(drive.Config{Type: *routerFlag, URL: *osrmURLFlag, Store: cs, Policy: backend.CachePolicy(q.Policy)})
+	if err != nil {
+		return fmt.Errorf("router: %w", err)
+	}
+
+	ms, errs := search.Run(*providersFlag, q, cs, search.NewIndex(srcs, props), rt)
 
 	fmap := template.FuncMap{
 		"Ellipsis": ellipse,
@@ -103,6 +148,7 @@ func processFlags() error {
 		"hmagenta": func(s string) string { return ansi.Color(s, "magenta+h") },
 		"hwhite":   func(s string) string { return ansi.Color(s, "white+h") },
 		"grey":     func(s string) string { return ansi.Color(s, "black+h") },
+		"drive":    drive.Summary,
 	}
 
 	t := template.Must(template.New("ascii").Funcs(fmap).Parse(outTmpl))
@@ -118,6 +164,96 @@ func processFlags() error {
 	return err
 }
 
+// query returns the query described by the command-line flags
+func query() (campwiz.Query, error) {
+	policy, err := campwiz.ParseFetchPolicy(*policyFlag)
+	if err != nil {
+		return campwiz.Query{}, err
+	}
+
+	q := campwiz.Query{
+		Lon:          *lonFlag,
+		Lat:          *latFlag,
+		StayLength:   *nightsFlag,
+		MaxDistance:  *milesFlag,
+		MaxDriveTime: *maxDriveFlag,
+		MinRating:    *minRatingFlag,
+		Keywords:     *keywordsFlag,
+		Sort:         *sortFlag,
+		Policy:       policy,
+	}
+
+	ws, err := rank.ParseWeights(*sortWeightsFlag)
+	if err != nil {
+		return q, fmt.Errorf("sort weights: %w", err)
+	}
+	q.SortWeights = ws
+
+	if _, err := rank.New(q.Sort, ws); err != nil {
+		return q, err
+	}
+
+	if _, err := search.Keywords(q); err != nil {
+		return q, err
+	}
+
+	for _, s := range *originsFlag {
+		o, err := campwiz.ParseOrigin(s)
+		if err != nil {
+			return q, err
+		}
+		q.Origins = append(q.Origins, o)
+	}
+	if q.OriginDistance, err = campwiz.ParseOriginDistance(*originDistFlag); err != nil {
+		return q, err
+	}
+
+	if q.Route, err = route(); err != nil {
+		return q, err
+	}
+	if len(q.Route) > 0 {
+		if len(q.Origins) > 0 {
+			return q, fmt.Errorf("--origin can not be combined with --route or --gpx")
+		}
+		q.MaxDetour = *maxDetourFlag
+		if !pflag.CommandLine.Changed("sort") {
+			q.Sort = rank.Route
+		}
+	}
+
+	for _, ds := range *datesFlag {
+		t, err := time.Parse(dateFormat, ds)
+		if err != nil {
+			return q, fmt.Errorf("unable to parse date %q: %w", ds, err)
+		}
+		q.Dates = append(q.Dates, t)
+	}
+	return q, nil
+}
+
+// route returns the path given by --route or --gpx, if any
+func route() ([]geo.Point, error) {
+	switch {
+	case *routeFlag != "" && *gpxFlag != "":
+		return nil, fmt.Errorf("--route can not be combined with --gpx")
+	case *routeFlag != "":
+		return geo.ParsePath(*routeFlag)
+	case *gpxFlag != "":
+		f, err := os.Open(*gpxFlag)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		path, err := geo.ParseGPX(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", *gpxFlag, err)
+		}
+		return path, nil
+	}
+	return nil, nil
+}
+
 func ellipse(s string) string {
 	return mangle.Ellipsis(s, 100)
 }
@@ -132,6 +268,27 @@ func main() {
 	pflag.Set("alsologtostderr", "false")
 	pflag.Parse()
 
+	if pflag.Arg(0) == "cache" {
+		if err := cacheCmd(pflag.Args()[1:]); err != nil {
+			klog.Exitf("cache error: %v", err)
+		}
+		return
+	}
+
+	if pflag.Arg(0) == "bind" {
+		if err := bindCmd(pflag.Args()[1:]); err != nil {
+			klog.Exitf("bind error: %v", err)
+		}
+		return
+	}
+
+	if pflag.Arg(0) == "explain" {
+		if err := explainCmd(pflag.Args()[1:]); err != nil {
+			klog.Exitf("explain error: %v", err)
+		}
+		return
+	}
+
 	if err := processFlags(); err != nil {
 		klog.Exitf("processing error: %v", err)
 	}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/metadata"
+	"github.com/tstromberg/campwiz/pkg/search"
+)
+
+const explainUsage = `usage: cw explain "<result name>"`
+
+// explainCmd implements "cw explain", showing how a result name is matched to known campgrounds
+func explainCmd(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf(explainUsage)
+	}
+	name := strings.Join(args, " ")
+
+	srcs, props, err := metadata.LoadAll()
+	if err != nil {
+		return fmt.Errorf("loadall failed: %w", err)
+	}
+
+	ms := search.Explain(campwiz.Result{Name: name}, search.NewIndex(srcs, props))
+	if len(ms) == 0 {
+		fmt.Printf("%q does not match any known campground\n", name)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "#\tSCORE\tPROPERTY\tCAMPGROUND\tDETAIL")
+	for i, m := range ms {
+		cg := "-"
+		prop := "-"
+		if m.Campground != nil {
+			cg = fmt.Sprintf("%s (%s)", m.Campground.Name, m.Campground.ID)
+			prop = m.Campground.PropertyID
+		}
+		fmt.Fprintf(w, "%d\t%s (%d)\t%s\t%s\t%s\n", i+1, search.ScoreName(m.Score), m.Score, prop, cg, m.Detail)
+	}
+	return nil
+}
@@ -10,7 +10,9 @@ import (
 	pflag "github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
+	"github.com/tstromberg/campwiz/pkg/backend"
 	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/drive"
 	"github.com/tstromberg/campwiz/pkg/metadata"
 	"github.com/tstromberg/campwiz/pkg/relpath"
 	"github.com/tstromberg/campwiz/pkg/search"
@@ -18,12 +20,19 @@ import (
 )
 
 var (
-	persistBackendFlag           = pflag.String("persist-backend", "", "Cache persistence backend (disk, mysql, cloudsql)")
-	persistPathFlag              = pflag.String("persist-path", "", "Where to persist cache to (automatic)")
+	persistBackendFlag           = pflag.String("persist-backend", "", "Cache persistence backend (disk, sqlite, mysql, cloudsql, memory)")
+	persistPathFlag              = pflag.String("persist-path", "", "Where to persist cache to: directory, file, or DSN (automatic)")
+	memoryEntriesFlag            = pflag.Int("memory-entries", 2000, "Number of cache entries to keep in memory (0 to disable)")
+	memoryBytesFlag              = pflag.Int64("memory-bytes", 0, "Number of cached bytes to keep in memory (0 for unlimited)")
+	memoryTTLFlag                = pflag.Duration("memory-ttl", 0, "How long to keep cache entries in memory (0 for long enough to serve them while stale)")
+	adminFlag                    = pflag.Bool("admin", false, "Serve the /cache and /har administration pages, which expose upstream responses (trusted networks only)")
+	harDirFlag                   = pflag.String("har-dir", "", "Directory to record upstream traffic to for searches with ?har=1 (disabled if empty)")
 	portFlag                     = pflag.Int("port", 8080, "port to run server at")
 	siteFlag                     = pflag.String("site", "site/", "path to site files")
 	thirdPartyFlag               = pflag.String("3p", "third_party/", "path to 3rd party files")
 	providersFlag      *[]string = pflag.StringSlice("providers", search.DefaultProviders, "site providers to include")
+	routerFlag                   = pflag.String("router", "heuristic", "How to estimate drive times: heuristic, osrm (falling back to heuristic), or none")
+	osrmURLFlag                  = pflag.String("osrm-url", "https://router.project-osrm.org", "Base URL of an OSRM-compatible server, for --router=osrm")
 
 	latFlag *float64 = pflag.Float64("lat", 37.4092297, "latitude to search from")
 	lonFlag *float64 = pflag.Float64("lon", -122.07237049999999, "longitude to search from")
@@ -34,7 +43,24 @@ func main() {
 	pflag.CommandLine.AddGoFlagSet(goflag.CommandLine)
 	pflag.Parse()
 
-	cs, err := cache.New(cache.Config{MaxAge: cache.RecommendedMaxAge})
+	persistBackend := *persistBackendFlag
+	if persistBackend == "" {
+		persistBackend = os.Getenv("PERSIST_BACKEND")
+	}
+	path := *persistPathFlag
+	if path == "" {
+		path = os.Getenv("PERSIST_PATH")
+	}
+
+	cs, err := cache.New(cache.Config{
+		MaxAge:        cache.RecommendedMaxAge,
+		Backend:       persistBackend,
+		Path:          path,
+		StaleAge:      backend.MaxStaleAge(),
+		MemoryEntries: *memoryEntriesFlag,
+		MemoryBytes:   *memoryBytesFlag,
+		MemoryTTL:     *memoryTTLFlag,
+	})
 	if err != nil {
 		klog.Exitf("error: %w", err)
 	}
@@ -44,12 +70,21 @@ func main() {
 		klog.Exitf("loadall failed: %v", err)
 	}
 
+	rt, err := drive.New(drive.Config{Type: *routerFlag, URL: *osrmURLFlag, Store: cs})
+	if err != nil {
+		klog.Exitf("router: %v", err)
+	}
+
 	s := site.New(&site.Config{
 		BaseDirectory: relpath.Find(*siteFlag),
 		Cache:         cs,
 		Sources:       srcs,
 		Properties:    props,
+		Index:         search.NewIndex(srcs, props),
 		Providers:     *providersFlag,
+		HARDirectory:  *harDirFlag,
+		Router:        rt,
+		Admin:         *adminFlag,
 		Latitude:      *latFlag,
 		Longitude:     *lonFlag,
 	})
@@ -61,6 +96,10 @@ func main() {
 
 	http.HandleFunc("/", s.Root())
 	http.HandleFunc("/search", s.Search())
+	if *adminFlag {
+		http.HandleFunc("/cache", s.Cache())
+		http.HandleFunc("/har", s.HAR())
+	}
 	http.HandleFunc("/healthz", s.Healthz())
 	http.HandleFunc("/threadz", s.Threadz())
 	klog.Infof("Listening at: %s", listenAddr)
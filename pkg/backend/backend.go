@@ -19,6 +19,16 @@ var (
 
 	// maximum number of pages to fetch
 	maxPages = 15
+
+	// staleWindows is how long past expiry each providers search pages may be served
+	// while they are refreshed in the background.
+	staleWindows = map[string]time.Duration{
+		"ramerica":       2 * time.Hour,
+		"rcalifornia":    2 * time.Hour,
+		"rcaliforniaAdv": 2 * time.Hour,
+		"scc":            6 * time.Hour,
+		"smc":            6 * time.Hour,
+	}
 )
 
 // Provider is a common interface for backend providers
@@ -61,6 +71,50 @@ func New(c Config) (Provider, error) {
 	}
 }
 
+// CachePolicy returns the cache policy which fetches pages as a query's fetch policy asks.
+// An unset fetch policy leaves the cache to its default.
+func CachePolicy(p campwiz.FetchPolicy) cache.Policy {
+	switch p {
+	case campwiz.FetchNormal:
+		return cache.PolicyNormal
+	case campwiz.FetchCacheOnly:
+		return cache.PolicyCacheOnly
+	case campwiz.FetchRefresh:
+		return cache.PolicyRefresh
+	}
+	return ""
+}
+
+// MaxStaleAge returns the longest past expiry that any providers search pages may be served
+func MaxStaleAge() time.Duration {
+	var max time.Duration
+	for _, d := range staleWindows {
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// staleAge returns how long past expiry a providers search pages may be served for a query
+func staleAge(provider string, q campwiz.Query) time.Duration {
+	if !q.ServeStale {
+		return 0
+	}
+	return staleWindows[provider]
+}
+
+// refreshing flags results which were parsed from a stale response
+func refreshing(rs []campwiz.Result, resp cache.Response) []campwiz.Result {
+	if !resp.Stale {
+		return rs
+	}
+	for i := range rs {
+		rs[i].Refreshing = true
+	}
+	return rs
+}
+
 // mergeDates merges multiple dates together
 func mergeDates(res []campwiz.Result) []campwiz.Result {
 	klog.V(1).Infof("Merging %d results ...", len(res))
@@ -70,6 +124,7 @@ func mergeDates(res []campwiz.Result) []campwiz.Result {
 		if val, exists := m[key]; exists {
 			klog.V(1).Infof("%s: Appending Availability: %+v (previous: %+v)", key, r.Availability, val.Availability)
 			val.Availability = append(val.Availability, r.Availability...)
+			val.Refreshing = val.Refreshing || r.Refreshing
 			// map items are immutable.
 			m[key] = val
 			klog.V(1).Infof("%s campwiz.Availability now: %+v", key, m[key].Availability)
@@ -26,7 +26,7 @@ func (b *Empty) Name() string {
 // List lists available sites
 func (b *Empty) List(q campwiz.Query) ([]campwiz.Result, error) {
 	klog.Infof("Empty.List: %+v", q)
-	_, err := cache.Fetch(b.startPage(), b.store)
+	_, err := cache.Fetch(b.startPage(q), b.store)
 	if err != nil {
 		return nil, fmt.Errorf("fetch start: %w", err)
 	}
@@ -54,6 +54,7 @@ func (b *Empty) req(c campwiz.Query, arrival time.Time) cache.Request {
 		URL:      b.url("/search"),
 		Referrer: b.url("/"),
 		Jar:      b.jar,
+		Policy:   CachePolicy(c.Policy),
 		Form: url.Values{
 			"lng": {fmt.Sprintf("%3.3f", c.Lon)},  // Longitude
 			"lat": {fmt.Sprintf("%3.3f", c.Lat)},  // Latitude
@@ -64,8 +65,8 @@ func (b *Empty) req(c campwiz.Query, arrival time.Time) cache.Request {
 }
 
 // startPage generates an initial page request
-func (b *Empty) startPage() cache.Request {
-	return cache.Request{URL: b.url("/start"), Referrer: b.url("/"), Jar: b.jar}
+func (b *Empty) startPage(q campwiz.Query) cache.Request {
+	return cache.Request{URL: b.url("/start"), Referrer: b.url("/"), Jar: b.jar, Policy: CachePolicy(q.Policy)}
 }
 
 // parse parses the search response
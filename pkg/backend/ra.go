@@ -13,6 +13,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// raKey ignores session cookies when caching
+var raKey = cache.SignificantKey(nil, nil)
+
 // RAmerica handles RAmerica queries
 type RAmerica struct {
 	store cache.Store
@@ -27,7 +30,7 @@ func (b *RAmerica) Name() string {
 // List lists available sites
 func (b *RAmerica) List(q campwiz.Query) ([]campwiz.Result, error) {
 	klog.Infof("RAmerica.List: %+v", q)
-	_, err := cache.Fetch(b.startPage(), b.store)
+	_, err := cache.Fetch(b.startPage(q), b.store)
 	if err != nil {
 		return nil, fmt.Errorf("fetch start: %w", err)
 	}
@@ -52,9 +55,13 @@ func (b *RAmerica) url(s string) string {
 // req generates a search request
 func (b *RAmerica) req(c campwiz.Query, arrival time.Time, num int) cache.Request {
 	return cache.Request{
+		Provider: "ramerica",
 		URL:      b.url("/jaxrs-json/search"),
 		Referrer: b.url("/"),
 		Jar:      b.jar,
+		KeyFunc:  raKey,
+		StaleAge: staleAge("ramerica", c),
+		Policy:   CachePolicy(c.Policy),
 		Form: url.Values{
 			"rcp":     {strconv.Itoa(num)},            // page number
 			"stype":   {"nearby"},                     // search type
@@ -72,8 +79,8 @@ func (b *RAmerica) req(c campwiz.Query, arrival time.Time, num int) cache.Reques
 }
 
 // startPage generates an initial page request
-func (b *RAmerica) startPage() cache.Request {
-	return cache.Request{URL: b.url("/explore/search-results"), Referrer: b.url("/"), Jar: b.jar}
+func (b *RAmerica) startPage(q campwiz.Query) cache.Request {
+	return cache.Request{Provider: "ramerica", URL: b.url("/explore/search-results"), Referrer: b.url("/"), Jar: b.jar, KeyFunc: raKey, Policy: CachePolicy(q.Policy)}
 }
 
 type raControl struct {
@@ -93,10 +100,16 @@ type raAvailability struct {
 	ReservableType string
 }
 
+type raCoordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
 type raDetails struct {
 	BaseURL      string
 	ImageURL     string // relative URL
 	Availability raAvailability
+	Coordinates  raCoordinates
 }
 
 type raResponse struct {
@@ -147,6 +160,8 @@ func (b *RAmerica) parse(bs []byte, date time.Time, q campwiz.Query) ([]campwiz.
 			ResID:        r.NamingID,
 			Name:         r.Name,
 			Distance:     r.Proximity,
+			Lat:          r.Details.Coordinates.Latitude,
+			Lon:          r.Details.Coordinates.Longitude,
 			Availability: []campwiz.Availability{a},
 		}
 
@@ -181,7 +196,7 @@ func (b *RAmerica) avail(q campwiz.Query, d time.Time) ([]campwiz.Result, error)
 			return nil, fmt.Errorf("got page %d, expected page %d", currentPage, i)
 		}
 
-		results = append(results, prs...)
+		results = append(results, refreshing(prs, resp)...)
 
 		if currentPage >= totalPages-1 {
 			break
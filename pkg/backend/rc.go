@@ -12,6 +12,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// rcKey ignores session cookies when caching
+var rcKey = cache.SignificantKey(nil, nil)
+
 // RCalifornia handles RCalifornia queries
 type RCalifornia struct {
 	store cache.Store
@@ -65,12 +68,16 @@ func (b *RCalifornia) req(q campwiz.Query, arrival time.Time) (cache.Request, er
 	}
 
 	r := cache.Request{
+		Provider:    "rcalifornia",
 		Method:      "POST",
 		URL:         "https://calirdr.usedirect.com/rdr/rdr/search/place",
 		Referrer:    b.url("/"),
 		MaxAge:      searchPageExpiry,
+		StaleAge:    staleAge("rcalifornia", q),
+		Policy:      CachePolicy(q.Policy),
 		ContentType: "application/json",
 		Body:        body,
+		KeyFunc:     rcKey,
 	}
 
 	return r, nil
@@ -144,6 +151,8 @@ func (b *RCalifornia) parse(bs []byte, date time.Time, q campwiz.Query) ([]campw
 			Desc:         r.Description,
 			Features:     strings.Split(strings.TrimSuffix(r.AllHighlights, "<br>"), "<br>"),
 			Distance:     float64(r.MilesFromSelected),
+			Lat:          r.Latitude,
+			Lon:          r.Longitude,
 			Availability: []campwiz.Availability{a},
 			URL:          r.URL,
 			ImageURL:     r.ImageURL,
@@ -172,6 +181,7 @@ func (b *RCalifornia) avail(q campwiz.Query, d time.Time) ([]campwiz.Result, err
 	if err != nil {
 		return nil, fmt.Errorf("parse: %w", err)
 	}
+	results = refreshing(results, resp)
 
 	klog.Infof("returning %d results", len(results))
 	return results, nil
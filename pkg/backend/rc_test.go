@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
 )
@@ -33,6 +34,7 @@ func TestRCaliforniaReq(t *testing.T) {
 	t.Logf("body: %s", got.Body)
 
 	want := cache.Request{
+		Provider:    "rcalifornia",
 		Method:      "POST",
 		URL:         "https://calirdr.usedirect.com/rdr/rdr/search/place",
 		Referrer:    "https://www.reservecalifornia.com/",
@@ -41,7 +43,7 @@ func TestRCaliforniaReq(t *testing.T) {
 		Body:        []byte(`{"PlaceId":0,"Latitude":"37.4092","Longitude":"-122.0724","HighlightedPlaceId":0,"StartDate":"02-12-2021","Nights":"4","CountNearby":true,"NearbyLimit":100,"NearbyOnlyAvailable":true,"NearbyCountLimit":100,"Sort":"Distance","CustomerID":"0","RefreshFavourites":true,"IsADA":false,"UnitCategoryId":0,"SleepingUnitId":0,"MinVehicleLength":0,"UnitTypeGroupIds":null}`),
 	}
 
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(cache.Request{}, "KeyFunc")); diff != "" {
 		t.Errorf("rcPageRequest() mismatch (-want +got):\n%s", diff)
 	}
 }
@@ -14,6 +14,9 @@ import (
 	"k8s.io/klog/v2"
 )
 
+// rcaKey ignores session cookies when caching
+var rcaKey = cache.SignificantKey(nil, nil)
+
 // RCaliforniaAdv handles RCaliforniaAdv queries
 type RCaliforniaAdv struct {
 	store cache.Store
@@ -95,12 +98,16 @@ func (b *RCaliforniaAdv) req(q campwiz.Query, arrival time.Time) (cache.Request,
 	}
 
 	r := cache.Request{
+		Provider:    "rcaliforniaAdv",
 		Method:      "POST",
 		URL:         "https://www.reservecalifornia.com/CaliforniaWebHome/Facilities/AdvanceSearch.aspx/GetPlaceData",
 		Referrer:    "https://www.reservecalifornia.com/CaliforniaWebHome/Facilities/AdvanceSearch.aspx",
 		MaxAge:      searchPageExpiry,
+		StaleAge:    staleAge("rcaliforniaAdv", q),
+		Policy:      CachePolicy(q.Policy),
 		ContentType: "application/json",
 		Body:        body,
+		KeyFunc:     rcaKey,
 	}
 
 	return r, nil
@@ -163,6 +170,8 @@ func (b *RCaliforniaAdv) parse(bs []byte, date time.Time, q campwiz.Query) ([]ca
 			URL:          p.URL,
 			Features:     mangle.Features(p.Highlights),
 			Distance:     float64(p.Distance),
+			Lat:          p.Latitude,
+			Lon:          p.Longitude,
 			ImageURL:     p.ImageURL,
 			Availability: []campwiz.Availability{},
 		}
@@ -23,6 +23,12 @@ var (
 	sccCenterLat = 37.1908873
 	// sccCenterLon is the center of Santa Clara County, used for approximate location filtering
 	sccCenterLon = -122.4130398
+
+	// sccKey ignores calendar parameters derived from the current date, and session cookies, when caching
+	sccKey = cache.SignificantKey([]string{
+		"actiontype", "park_idno", "use_type", "res_length", "arrive_date", "c_park_idno",
+		"d_park_idno", "b_park_idno", "center_idno", "facility_use_type_idno",
+	}, nil)
 )
 
 // SantaClaraCounty handles SantaClaraCounty queries
@@ -39,7 +45,7 @@ func (b *SantaClaraCounty) Name() string {
 // List lists available sites
 func (b *SantaClaraCounty) List(q campwiz.Query) ([]campwiz.Result, error) {
 	klog.Infof("SantaClaraCounty.List: %+v", q)
-	_, err := cache.Fetch(b.startPage(), b.store)
+	_, err := cache.Fetch(b.startPage(q), b.store)
 	if err != nil {
 		return nil, fmt.Errorf("fetch start: %w", err)
 	}
@@ -84,18 +90,22 @@ func (b *SantaClaraCounty) req(q campwiz.Query, arrival time.Time) cache.Request
 	klog.Infof("values: %+v", v)
 
 	r := cache.Request{
+		Provider: "scc",
 		Method:   "GET",
 		URL:      b.url("/index.asp"),
 		Referrer: b.url("/"),
 		Form:     v,
 		Jar:      b.jar,
+		KeyFunc:  sccKey,
+		StaleAge: staleAge("scc", q),
+		Policy:   CachePolicy(q.Policy),
 	}
 	return r
 }
 
 // searchReq generates an initial page request
-func (b *SantaClaraCounty) startPage() cache.Request {
-	return cache.Request{URL: b.url("/index.asp"), Referrer: b.url("/"), Jar: b.jar}
+func (b *SantaClaraCounty) startPage(q campwiz.Query) cache.Request {
+	return cache.Request{Provider: "scc", URL: b.url("/index.asp"), Referrer: b.url("/"), Jar: b.jar, KeyFunc: sccKey, Policy: CachePolicy(q.Policy)}
 }
 
 // parse parses the search response
@@ -199,7 +209,7 @@ func (b *SantaClaraCounty) avail(q campwiz.Query, d time.Time) ([]campwiz.Result
 		return nil, nil
 	}
 
-	_, err := cache.Fetch(b.startPage(), b.store)
+	_, err := cache.Fetch(b.startPage(q), b.store)
 	if err != nil {
 		return nil, fmt.Errorf("fetch start: %w", err)
 	}
@@ -215,5 +225,5 @@ func (b *SantaClaraCounty) avail(q campwiz.Query, d time.Time) ([]campwiz.Result
 		return nil, fmt.Errorf("parse: %w", err)
 	}
 
-	return prs, err
+	return refreshing(prs, resp), err
 }
@@ -1,11 +1,14 @@
 package backend
 
 import (
+	"errors"
 	"io/ioutil"
+	"net/http"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
 )
 
@@ -176,3 +179,45 @@ func TestSantaClaraCountyParse(t *testing.T) {
 		t.Errorf("parseResp() mismatch (-want +got):\n%s\nraw: %+v\n", diff, got)
 	}
 }
+
+func TestSantaClaraCountyKey(t *testing.T) {
+	date, err := time.Parse("2006-01-02", "2021-02-12")
+	if err != nil {
+		t.Fatalf("time parse: %v", err)
+	}
+	q := campwiz.Query{StayLength: 4}
+
+	b := &SantaClaraCounty{}
+	first := b.req(q, date)
+	first.Form.Set("CalendarCurrentDate", "02/01/2021")
+	// searching the next day with a new session
+	second := b.req(q, date)
+	second.Form.Set("CalendarCurrentDate", "02/02/2021")
+	second.Cookies = []*http.Cookie{{Name: "ASPSESSIONID", Value: "abc"}}
+
+	if first.Key() != second.Key() {
+		t.Errorf("identical searches have different keys: %q vs %q", first.Key(), second.Key())
+	}
+
+	if other := b.req(q, date.Add(24*time.Hour)); other.Key() == first.Key() {
+		t.Errorf("different searches have the same key: %q", first.Key())
+	}
+}
+
+func TestSantaClaraCountyCacheOnly(t *testing.T) {
+	b, err := New(Config{Type: "scc", Store: cache.NewMemory(cache.MemoryConfig{})})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	date, err := time.Parse("2006-01-02", "2021-02-12")
+	if err != nil {
+		t.Fatalf("time parse: %v", err)
+	}
+
+	// With nothing cached, no network requests should be attempted
+	_, err = b.List(campwiz.Query{Dates: []time.Time{date}, StayLength: 2, Policy: campwiz.FetchCacheOnly})
+	if !errors.Is(err, cache.ErrNotCached) {
+		t.Errorf("List() error = %v, want %v", err, cache.ErrNotCached)
+	}
+}
@@ -20,6 +20,9 @@ var (
 	smcSiteIDs   = []string{"coyote-point", "huddart-park"}
 	smcCenterLat = 37.4250399
 	smcCenterLon = -122.4130398
+
+	// smcKey ignores the random code parameter and session cookies when caching
+	smcKey = cache.SignificantKey([]string{"startDate", "endDate"}, nil)
 )
 
 // SanMateoCounty handles Santa Mateo County Parks queries
@@ -37,7 +40,7 @@ func (b *SanMateoCounty) Name() string {
 func (b *SanMateoCounty) List(q campwiz.Query) ([]campwiz.Result, error) {
 	var res []campwiz.Result
 	for _, siteID := range smcSiteIDs {
-		_, err := cache.Fetch(b.startPage(siteID), b.store)
+		_, err := cache.Fetch(b.startPage(q, siteID), b.store)
 		if err != nil {
 			return nil, fmt.Errorf("fetch start: %w", err)
 		}
@@ -60,8 +63,8 @@ func (b *SanMateoCounty) url(s string) string {
 }
 
 // startPage generates an initial page request
-func (b *SanMateoCounty) startPage(siteID string) cache.Request {
-	return cache.Request{URL: b.url("/" + siteID), Referrer: b.url("/"), Jar: b.jar}
+func (b *SanMateoCounty) startPage(q campwiz.Query, siteID string) cache.Request {
+	return cache.Request{Provider: "smc", URL: b.url("/" + siteID), Referrer: b.url("/"), Jar: b.jar, KeyFunc: smcKey, Policy: CachePolicy(q.Policy)}
 }
 
 // req generates a search request
@@ -73,12 +76,16 @@ func (b *SanMateoCounty) req(q campwiz.Query, arrival time.Time, siteID string)
 	}
 
 	r := cache.Request{
+		Provider: "smc",
 		Method:   "GET",
 		URL:      b.url("/campsites/feed.html"),
 		Referrer: b.url("/" + siteID),
 		Form:     v,
 		MaxAge:   searchPageExpiry,
+		StaleAge: staleAge("smc", q),
+		Policy:   CachePolicy(q.Policy),
 		Jar:      b.jar,
+		KeyFunc:  smcKey,
 	}
 	return r
 }
@@ -155,5 +162,5 @@ func (b *SanMateoCounty) avail(q campwiz.Query, d time.Time, siteID string) ([]c
 		return nil, fmt.Errorf("parse: %w", err)
 	}
 
-	return prs, err
+	return refreshing(prs, resp), err
 }
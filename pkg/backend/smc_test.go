@@ -2,11 +2,13 @@ package backend
 
 import (
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"testing"
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
 )
@@ -98,6 +100,7 @@ func TestSMCSiteRequest(t *testing.T) {
 	got := b.req(q, date, "coyote-point")
 
 	want := cache.Request{
+		Provider: "smc",
 		Method:   "GET",
 		URL:      "https://secure.itinio.com/sanmateo/campsites/feed.html",
 		Referrer: "https://secure.itinio.com/sanmateo/coyote-point",
@@ -110,7 +113,30 @@ func TestSMCSiteRequest(t *testing.T) {
 		},
 	}
 
-	if diff := cmp.Diff(want, got); diff != "" {
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(cache.Request{}, "KeyFunc")); diff != "" {
 		t.Errorf("rcPageRequest() mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestSMCKey(t *testing.T) {
+	date, err := time.Parse("2006-01-02", "2021-02-12")
+	if err != nil {
+		t.Fatalf("time parse: %v", err)
+	}
+	q := campwiz.Query{StayLength: 4}
+
+	b := &SanMateoCounty{}
+	first := b.req(q, date, "coyote-point")
+	// the code parameter is random for every request
+	second := b.req(q, date, "coyote-point")
+	second.Cookies = []*http.Cookie{{Name: "session", Value: "abc"}}
+
+	if first.Key() != second.Key() {
+		t.Errorf("identical searches have different keys: %q vs %q", first.Key(), second.Key())
+	}
+
+	q.StayLength = 2
+	if other := b.req(q, date, "coyote-point"); other.Key() == first.Key() {
+		t.Errorf("different searches have the same key: %q", first.Key())
+	}
+}
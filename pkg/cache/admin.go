@@ -0,0 +1,201 @@
+package cache
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Entry describes a single entry within the cache
+type Entry struct {
+	Key  string
+	Size int
+	// Version is the format version the entry was stored with
+	Version int
+	// Compressed is true if the entry was stored compressed
+	Compressed bool
+	// Request summarizes the request the entry was stored for
+	Request RequestSummary
+	// Response is the decoded entry, if it could be decoded
+	Response Response
+	// Err is set if the entry could not be read or decoded
+	Err error
+}
+
+// Host returns the hostname the entry was fetched from
+func (e Entry) Host() string {
+	u, err := url.Parse(e.Response.URL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Age returns how old the entry is
+func (e Entry) Age() time.Duration {
+	if e.Response.MTime.IsZero() {
+		return 0
+	}
+	return time.Since(e.Response.MTime)
+}
+
+// Failing returns true if the entry is undecodable or stores an unsuccessful response
+func (e Entry) Failing() bool {
+	return e.Err != nil || e.Response.StatusCode >= 400
+}
+
+// Filter selects entries within the cache. Zero values match everything.
+type Filter struct {
+	Host       string
+	Provider   string
+	StatusCode int
+	// OlderThan matches entries older than this age
+	OlderThan time.Duration
+	// NewerThan matches entries newer than this age
+	NewerThan time.Duration
+	// Failing matches undecodable entries and unsuccessful responses
+	Failing bool
+}
+
+// Match returns true if the entry matches the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Failing && !e.Failing() {
+		return false
+	}
+	if f.Host != "" && e.Host() != f.Host {
+		return false
+	}
+	if f.Provider != "" && e.Response.Provider != f.Provider {
+		return false
+	}
+	if f.StatusCode != 0 && e.Response.StatusCode != f.StatusCode {
+		return false
+	}
+	if (f.OlderThan > 0 || f.NewerThan > 0) && e.Response.MTime.IsZero() {
+		return false
+	}
+	if f.OlderThan > 0 && e.Age() < f.OlderThan {
+		return false
+	}
+	if f.NewerThan > 0 && e.Age() > f.NewerThan {
+		return false
+	}
+	return true
+}
+
+// Inspect reads and decodes a single cache entry. Stores which can be peeked at are,
+// so that inspecting the cache does not disturb which entries are kept in memory.
+func Inspect(cs Store, key string) Entry {
+	e := Entry{Key: key}
+	read := cs.Read
+	if p, ok := cs.(Peeker); ok {
+		read = p.Peek
+	}
+	bs, err := read(key)
+	if err != nil {
+		e.Err = fmt.Errorf("read: %w", err)
+		return e
+	}
+	e.Size = len(bs)
+
+	env, err := decode(bs)
+	if err != nil {
+		e.Err = fmt.Errorf("decode: %w", err)
+		return e
+	}
+
+	e.Version = env.version
+	e.Compressed = env.compressed
+	e.Request = env.Request
+	e.Response = env.Response
+	return e
+}
+
+// List returns cache entries matching a filter, newest first
+func List(cs Lister, f Filter) ([]Entry, error) {
+	keys, err := cs.Keys()
+	if err != nil {
+		return nil, fmt.Errorf("keys: %w", err)
+	}
+
+	es := []Entry{}
+	for _, k := range keys {
+		e := Inspect(cs, k)
+		if f.Match(e) {
+			es = append(es, e)
+		}
+	}
+
+	sort.Slice(es, func(i, j int) bool {
+		if es[i].Response.MTime.Equal(es[j].Response.MTime) {
+			return es[i].Key < es[j].Key
+		}
+		return es[i].Response.MTime.After(es[j].Response.MTime)
+	})
+	return es, nil
+}
+
+// Purge deletes cache entries matching a filter, returning the entries deleted
+func Purge(cs Lister, f Filter) ([]Entry, error) {
+	es, err := List(cs, f)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range es {
+		if err := cs.Delete(e.Key); err != nil {
+			return es[:i], fmt.Errorf("delete %s: %w", e.Key, err)
+		}
+	}
+	return es, nil
+}
+
+// Summary describes a set of cache entries
+type Summary struct {
+	Entries    int
+	Bytes      int64
+	Failing    int
+	Legacy     int
+	Oldest     time.Time
+	Newest     time.Time
+	ByHost     map[string]int
+	ByProvider map[string]int
+	ByStatus   map[int]int
+}
+
+// Summarize returns a summary of cache entries
+func Summarize(es []Entry) Summary {
+	s := Summary{
+		ByHost:     map[string]int{},
+		ByProvider: map[string]int{},
+		ByStatus:   map[int]int{},
+	}
+
+	for _, e := range es {
+		s.Entries++
+		s.Bytes += int64(e.Size)
+		if e.Failing() {
+			s.Failing++
+		}
+		if e.Err != nil {
+			continue
+		}
+		if e.Version < entryVersion {
+			s.Legacy++
+		}
+
+		s.ByHost[e.Host()]++
+		s.ByProvider[e.Response.Provider]++
+		s.ByStatus[e.Response.StatusCode]++
+
+		mt := e.Response.MTime
+		if s.Oldest.IsZero() || mt.Before(s.Oldest) {
+			s.Oldest = mt
+		}
+		if mt.After(s.Newest) {
+			s.Newest = mt
+		}
+	}
+	return s
+}
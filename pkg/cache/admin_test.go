@@ -0,0 +1,130 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAdmin(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		fmt.Fprintln(w, "hi")
+	}))
+	defer ts.Close()
+
+	cs, err := New(Config{Backend: "sqlite", Path: filepath.Join(t.TempDir(), "cache.db"), MaxAge: RecommendedMaxAge})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	l := cs.(Lister)
+
+	for _, r := range []Request{
+		{URL: ts.URL + "/ok", Provider: "ramerica"},
+		{URL: ts.URL + "/broken", Provider: "ramerica"},
+		{URL: ts.URL + "/other", Provider: "scc"},
+	} {
+		if _, err := Fetch(r, cs); err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+	}
+	if err := cs.Write("garbage", []byte("not a gob")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want int
+	}{
+		{"all", Filter{}, 4},
+		{"provider", Filter{Provider: "ramerica"}, 2},
+		{"status", Filter{StatusCode: 500}, 1},
+		{"failing", Filter{Failing: true}, 2},
+		{"host", Filter{Host: "127.0.0.1"}, 3},
+		{"old", Filter{OlderThan: time.Hour}, 0},
+		{"new", Filter{NewerThan: time.Hour}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			es, err := List(l, tt.f)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if len(es) != tt.want {
+				t.Errorf("got %d entries, want %d: %+v", len(es), tt.want, es)
+			}
+		})
+	}
+
+	all, err := List(l, Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	s := Summarize(all)
+	if s.Entries != 4 || s.Failing != 2 || s.ByProvider["ramerica"] != 2 || s.ByStatus[200] != 2 {
+		t.Errorf("unexpected summary: %+v", s)
+	}
+
+	purged, err := Purge(l, Filter{Failing: true})
+	if err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if len(purged) != 2 {
+		t.Errorf("purged %d entries, want 2", len(purged))
+	}
+
+	left, err := List(l, Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(left) != 2 {
+		t.Errorf("got %d entries after purge, want 2", len(left))
+	}
+}
+
+// TestListLeavesMemory verifies that listing a persistent store does not fill its memory layer
+func TestListLeavesMemory(t *testing.T) {
+	cs, err := New(Config{Backend: "disk", Path: t.TempDir(), MaxAge: RecommendedMaxAge, MemoryEntries: 2})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	ms, ok := cs.(*MemoryStore)
+	if !ok {
+		t.Fatalf("got %T, want *MemoryStore", cs)
+	}
+
+	for _, k := range []string{"cold-1", "cold-2", "hot"} {
+		if err := ms.Write(k, []byte(k)); err != nil {
+			t.Fatalf("write %s: %v", k, err)
+		}
+	}
+	before := ms.Stats()
+
+	es, err := List(ms, Filter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(es) != 3 {
+		t.Errorf("got %d entries, want 3: %+v", len(es), es)
+	}
+	if e := Inspect(ms, "cold-1"); e.Size != len("cold-1") {
+		t.Errorf("Inspect(cold-1) = %+v, want it read from disk", e)
+	}
+
+	if after := ms.Stats(); after != before {
+		t.Errorf("listing changed memory stats from %+v to %+v", before, after)
+	}
+	if _, err := ms.Read("hot"); err != nil {
+		t.Fatalf("read hot: %v", err)
+	}
+	if st := ms.Stats(); st.Hits != before.Hits+1 {
+		t.Errorf("hot entry was evicted by listing: %+v", st)
+	}
+}
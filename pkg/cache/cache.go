@@ -4,7 +4,7 @@ package cache
 import (
 	"bytes"
 	"crypto/md5"
-	"encoding/gob"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,7 +17,7 @@ import (
 	"time"
 
 	"github.com/moul/http2curl"
-	"github.com/peterbourgon/diskv"
+	"golang.org/x/sync/singleflight"
 	"k8s.io/klog/v2"
 )
 
@@ -30,11 +30,49 @@ var (
 
 	// How long to cache by default: needs to be less than session cookie (12 hours is too long)
 	RecommendedMaxAge = 4 * time.Hour
-	defaultMaxAge     = RecommendedMaxAge
+
+	// errExpired is returned by tryCache for entries older than the maximum age
+	errExpired = errors.New("expired")
+
+	// ErrNotCached is returned for cache-only fetches of content which is not cached
+	ErrNotCached = errors.New("not cached")
 )
 
+// KeyFunc returns the cache key for a request
+type KeyFunc func(Request) string
+
+// Policy controls whether a fetch may use the cache or the network
+type Policy string
+
+const (
+	// PolicyNormal serves content from the cache until it expires
+	PolicyNormal Policy = "normal"
+	// PolicyCacheOnly serves content from the cache regardless of age, and never uses the network
+	PolicyCacheOnly Policy = "cache-only"
+	// PolicyRefresh ignores the cache, fetching and storing fresh content
+	PolicyRefresh Policy = "refresh"
+)
+
+// Policies are the available fetch policies
+var Policies = []Policy{PolicyNormal, PolicyCacheOnly, PolicyRefresh}
+
+// ParsePolicy parses a fetch policy name, defaulting to PolicyNormal
+func ParsePolicy(s string) (Policy, error) {
+	if s == "" {
+		return PolicyNormal, nil
+	}
+	for _, p := range Policies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fetch policy %q, expected one of %v", s, Policies)
+}
+
 // Request defines what can be passed in as a request
 type Request struct {
+	// Provider is the name of the backend making the request
+	Provider string
 	// Method type
 	Method string
 	// URL
@@ -48,28 +86,69 @@ type Request struct {
 	// POST form values
 	Form url.Values
 	// Maximum age of content.
-	MaxAge  time.Duration
-	Headers map[string]string
+	MaxAge time.Duration
+	// StaleAge is how long past MaxAge content may be served while it is revalidated in the background
+	StaleAge time.Duration
+	Headers  map[string]string
 	// POST info
 	ContentType string
 	Body        []byte
+	// KeyFunc optionally overrides how the cache key is calculated
+	KeyFunc KeyFunc
+	// Policy controls whether the cache or network may be used (defaults to PolicyNormal)
+	Policy Policy
 }
 
 // Key returns a cache-key.
 func (r Request) Key() string {
+	if r.KeyFunc != nil {
+		return r.KeyFunc(r)
+	}
+	return r.key(r.Form, r.Cookies)
+}
+
+// SignificantKey returns a KeyFunc which only considers the named form values and cookies,
+// for providers which send volatile values. If params is nil, all form values are considered.
+func SignificantKey(params []string, cookies []string) KeyFunc {
+	return func(r Request) string {
+		form := r.Form
+		if params != nil {
+			form = url.Values{}
+			for _, p := range params {
+				if v, ok := r.Form[p]; ok {
+					form[p] = v
+				}
+			}
+		}
+
+		var cs []*http.Cookie
+		for _, c := range r.Cookies {
+			for _, name := range cookies {
+				if c.Name == name {
+					cs = append(cs, c)
+				}
+			}
+		}
+		return r.key(form, cs)
+	}
+}
+
+// key returns a cache-key for a request with the given form values and cookies.
+func (r Request) key(form url.Values, cookies []*http.Cookie) string {
 	var buf bytes.Buffer
 
 	buf.WriteString(r.Method + " ")
-	buf.WriteString(r.URL + "?" + r.Form.Encode())
+	buf.WriteString(r.URL + "?" + form.Encode())
 
-	for _, c := range r.Cookies {
+	for _, c := range cookies {
 		buf.WriteString(fmt.Sprintf("+cookie=%s", c.String()))
 	}
 	if r.Referrer != "" {
 		buf.WriteString(fmt.Sprintf("+ref=%s", r.Referrer))
 	}
 
-	if len(r.Body) > 0 {
+	// POST bodies generated from form values are already represented
+	if len(r.Body) > 0 && len(r.Form) == 0 {
 		buf.WriteString(fmt.Sprintf("+body=%s", r.Body))
 	}
 
@@ -91,6 +170,8 @@ func (r Request) Key() string {
 type Response struct {
 	// URL result is from
 	URL string
+	// Provider is the name of the backend which made the request
+	Provider string
 	// Status Code
 	StatusCode int
 	// HTTP headers
@@ -103,45 +184,59 @@ type Response struct {
 	MTime time.Time
 	// If entry was served from cache
 	Cached bool
+	// If entry was served from cache past its maximum age, and is being revalidated
+	Stale bool
+	// If an expired entry was confirmed unchanged by the upstream (304 Not Modified)
+	Revalidated bool
 }
 
+// Store is a key/value store for cached responses
 type Store interface {
 	Read(string) ([]byte, error)
 	Write(string, []byte) error
 }
 
-// tryCache attempts a cache-only fetch.
+// Lister is a Store which can also enumerate and delete entries
+type Lister interface {
+	Store
+	Keys() ([]string, error)
+	Delete(string) error
+}
+
+// Peeker is a Store which can read an entry without counting the read or caching the entry
+type Peeker interface {
+	Peek(string) ([]byte, error)
+}
+
+// tryCache attempts a cache-only fetch. Expired responses are returned along with an errExpired error.
 func tryCache(req Request, cs Store) (Response, error) {
 	klog.V(3).Infof("tryCache: %+v", req)
 
-	var res Response
-	cachedBytes, err := cs.Read(req.Key())
+	// Invalid or unreadable items are treated as a miss
+	e, err := readEntry(cs, req.Key())
 	if err != nil {
-		return res, err
+		return Response{}, err
 	}
 
 	// Item is in cache, but we do not yet know if it is too old.
-	buf := bytes.NewBuffer(cachedBytes)
-	dec := gob.NewDecoder(buf)
-	err = dec.Decode(&res)
-	// Invalid item in cache?
-	if err != nil {
-		return res, err
-	}
+	res := e.Response
 
 	age := time.Since(res.MTime)
 	if age > req.MaxAge {
-		return res, fmt.Errorf("URL %s cache was too old", req.URL)
+		return res, fmt.Errorf("URL %s cache was too old: %w", req.URL, errExpired)
 	}
 	klog.V(2).Infof("Found %s at %s (cookies=%+v)", res.URL, req.Key(), res.Cookies)
 	return res, nil
 }
 
-// applyDefault applies default request options
-func applyDefaults(req Request) (Request, error) {
+// applyDefault applies default request options, including those configured for the store
+func applyDefaults(req Request, cs Store) (Request, error) {
 	// Apply defaults
 	if req.MaxAge == 0 {
-		req.MaxAge = defaultMaxAge
+		req.MaxAge = RecommendedMaxAge
+		if d, ok := cs.(configurable); ok {
+			req.MaxAge = d.MaxAge()
+		}
 	}
 	if req.Method == "" {
 		req.Method = "GET"
@@ -175,41 +270,134 @@ func applyDefaults(req Request) (Request, error) {
 // Fetch wraps http.Get/http.Post behind a persistent ca
 func Fetch(req Request, cs Store) (Response, error) {
 	klog.V(2).Infof("incoming fetch: %+v", req)
-	req, err := applyDefaults(req)
+	rs, recording := cs.(*recordingStore)
+	if recording {
+		cs = rs.Store
+	}
+
+	req, err := applyDefaults(req, cs)
 	if err != nil {
 		return Response{}, fmt.Errorf("apply defaults: %w", err)
 	}
 
-	encURL := req.URL
-	if req.Method == "GET" && len(req.Form) > 0 {
-		encURL = encURL + "?" + req.Form.Encode()
+	if recording {
+		start := time.Now()
+		res, err := lookup(req, cs)
+		rs.rec.add(req, res, err, start)
+		return res, err
+	}
+	return lookup(req, cs)
+}
+
+// lookup returns a response from the cache, or fetches it from upstream
+func lookup(req Request, cs Store) (Response, error) {
+	klog.V(1).Infof("fetching %s: %+v", req.URL, req)
+	if req.Policy == PolicyRefresh {
+		klog.Infof("REFRESH[%s]: ignoring cache", req.Key())
+		return coalesce(req, cs, nil)
 	}
 
-	klog.V(1).Infof("fetching %s: %+v", encURL, req)
 	res, err := tryCache(req, cs)
-	if err != nil {
-		klog.V(2).Infof("MISS[%s]: %+v, tryCache returned: %v", req.Key(), req, err)
-	} else {
-		klog.Infof("HIT[%s]: age: %s (max-age: %d)", req.Key(), time.Since(res.MTime), req.MaxAge)
-		klog.V(3).Infof("cached cookies: %v", res.Cookies)
-		klog.V(4).Infof("cached body: %s", res.Body)
-		res.Cached = true
-		u, err := url.Parse(res.URL)
-		if err != nil {
-			return Response{}, err
+	if req.Policy == PolicyCacheOnly {
+		if err != nil && !errors.Is(err, errExpired) {
+			return Response{}, fmt.Errorf("%s: %w", req.URL, ErrNotCached)
 		}
+		klog.Infof("CACHE-ONLY[%s]: age: %s", req.Key(), time.Since(res.MTime))
+		return useCached(req, res)
+	}
+
+	switch {
+	case err == nil:
+		klog.Infof("HIT[%s]: age: %s (max-age: %d)", req.Key(), time.Since(res.MTime), req.MaxAge)
+		return useCached(req, res)
+	case errors.Is(err, errExpired) && time.Since(res.MTime) <= req.MaxAge+req.StaleAge:
+		klog.Infof("STALE[%s]: age: %s (max-age: %s, stale-age: %s), revalidating", req.Key(), time.Since(res.MTime), req.MaxAge, req.StaleAge)
+		prev := validated(res)
+		go func() {
+			if _, err := coalesce(req, cs, prev); err != nil {
+				klog.Errorf("revalidate %s: %v", req.URL, err)
+			}
+		}()
+		res.Stale = true
+		return useCached(req, res)
+	case errors.Is(err, errExpired):
+		klog.Infof("EXPIRED[%s]: age: %s (max-age: %s)", req.Key(), time.Since(res.MTime), req.MaxAge)
+		return coalesce(req, cs, validated(res))
+	default:
+		klog.V(2).Infof("MISS[%s]: %+v, tryCache returned: %v", req.Key(), req, err)
+	}
+
+	return coalesce(req, cs, nil)
+}
+
+// validated returns the response if the upstream sent validators that can be used
+// for a conditional request, or nil if the entry must be refetched in full.
+func validated(res Response) *Response {
+	if res.StatusCode != http.StatusOK {
+		return nil
+	}
+	if res.Header.Get("ETag") == "" && res.Header.Get("Last-Modified") == "" {
+		return nil
+	}
+	return &res
+}
+
+// useCached returns a cached response, adding the cached cookies to the request jar
+func useCached(req Request, res Response) (Response, error) {
+	klog.V(3).Infof("cached cookies: %v", res.Cookies)
+	klog.V(4).Infof("cached body: %s", res.Body)
+	res.Cached = true
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		return Response{}, err
+	}
+
+	klog.Infof("adding %d cookies to jar for %s: %+v", len(res.Cookies), u, res.Cookies)
+	// Set the cookie for the entire site
+	u.Path = "/"
+	req.Jar.SetCookies(u, res.Cookies)
+	return res, nil
+}
+
+// coalesce performs an uncached fetch, sharing one upstream request and cache write
+// between concurrent callers requesting the same key from the same store.
+func coalesce(req Request, cs Store, prev *Response) (Response, error) {
+	d, ok := cs.(configurable)
+	if !ok {
+		return fetch(req, cs, prev)
+	}
+
+	v, err, shared := d.inflight().Do(req.Key(), func() (interface{}, error) {
+		return fetch(req, cs, prev)
+	})
+	res := v.(Response)
+	if err != nil || !shared {
+		return res, err
+	}
+
+	klog.V(1).Infof("shared upstream fetch of %s", req.Key())
+	u, err := url.Parse(res.URL)
+	if err != nil {
+		return res, err
+	}
+	// Callers waiting on another request need the cookies it was given
+	u.Path = "/"
+	req.Jar.SetCookies(u, res.Cookies)
+	return res, nil
+}
 
-		klog.Infof("adding %d cookies to jar for %s: %+v", len(res.Cookies), u, res.Cookies)
-		// Set the cookie for the entire site
-		u.Path = "/"
-		req.Jar.SetCookies(u, res.Cookies)
-		return res, nil
+// newHTTPRequest builds the upstream HTTP request for a cache request. If prev is set,
+// the request is made conditional on the validators it was stored with.
+func newHTTPRequest(req Request, prev *Response) (*http.Request, error) {
+	encURL := req.URL
+	if req.Method == "GET" && len(req.Form) > 0 {
+		encURL = encURL + "?" + req.Form.Encode()
 	}
 
 	getBody := bytes.NewBuffer(req.Body)
 	hr, err := http.NewRequest(req.Method, encURL, getBody)
 	if err != nil {
-		return res, err
+		return nil, err
 	}
 
 	if req.Referrer != "" {
@@ -224,7 +412,16 @@ func Fetch(req Request, cs Store) (Response, error) {
 
 	for _, c := range req.Cookies {
 		hr.AddCookie(c)
-		klog.Infof("Cookie: %s", c)
+		klog.V(2).Infof("Cookie: %s", c)
+	}
+
+	if prev != nil {
+		if etag := prev.Header.Get("ETag"); etag != "" {
+			hr.Header.Set("If-None-Match", etag)
+		}
+		if lm := prev.Header.Get("Last-Modified"); lm != "" {
+			hr.Header.Set("If-Modified-Since", lm)
+		}
 	}
 
 	if req.Method == "POST" {
@@ -234,18 +431,30 @@ func Fetch(req Request, cs Store) (Response, error) {
 			hr.Header.Add("Content-Type", req.ContentType)
 		}
 	}
+	return hr, nil
+}
 
-	cmd, err := http2curl.GetCurlCommand(hr)
+// fetch performs an uncached fetch, storing the response in the cache. If prev is set,
+// the fetch is made conditional on the validators it was stored with.
+func fetch(req Request, cs Store, prev *Response) (Response, error) {
+	hr, err := newHTTPRequest(req, prev)
 	if err != nil {
-		klog.Errorf("unable to convert to curl: %+v", req)
-	} else {
-		klog.Infof("debug: %s", cmd)
+		return Response{}, err
+	}
+
+	if klog.V(2).Enabled() {
+		cmd, err := http2curl.GetCurlCommand(hr)
+		if err != nil {
+			klog.Errorf("unable to convert to curl: %+v", req)
+		} else {
+			klog.Infof("debug: %s", cmd)
+		}
 	}
 
 	client := &http.Client{Jar: req.Jar}
 	r, err := client.Do(hr)
 	if err != nil {
-		return res, err
+		return Response{}, err
 	}
 	klog.V(2).Infof("r: %+v", r)
 
@@ -254,8 +463,13 @@ func Fetch(req Request, cs Store) (Response, error) {
 	if err != nil {
 		return Response{}, err
 	}
+	if prev != nil && r.StatusCode == http.StatusNotModified {
+		return notModified(req, cs, *prev, r)
+	}
+
 	cr := Response{
 		URL:        req.URL,
+		Provider:   req.Provider,
 		StatusCode: r.StatusCode,
 		Header:     r.Header,
 		Cookies:    req.Jar.Cookies(r.Request.URL),
@@ -265,59 +479,177 @@ func Fetch(req Request, cs Store) (Response, error) {
 
 	klog.Infof("Fetched %s, status=%d, cookies=%s, bytes=%d", req.URL, r.StatusCode, r.Cookies(), len(body))
 	for k, v := range r.Header {
-		klog.Infof("Response header: %s=%q", k, v)
+		klog.V(3).Infof("Response header: %s=%q", k, v)
 	}
 	for _, c := range cr.Cookies {
-		klog.Infof("CookieJar: %s", c)
+		klog.V(3).Infof("CookieJar: %s", c)
 	}
 
 	klog.V(2).Infof("body: %s", body)
 
-	var buf bytes.Buffer
-	enc := gob.NewEncoder(&buf)
-	err = enc.Encode(&cr)
+	bs, err := encode(newEnvelope(req, cr))
 	if err != nil {
 		return cr, fmt.Errorf("encoding %+v: %v", cr, err)
 	}
 
-	bufBytes, err := ioutil.ReadAll(&buf)
-	if err != nil {
-		klog.V(1).Infof("Failed to read back encoded response: %s", err)
-	} else {
-		klog.V(1).Infof("Storing %s", req.Key())
-		err := cs.Write(req.Key(), bufBytes)
-		if err != nil {
-			klog.Errorf("unable to write %s: %v", req.Key(), err)
-			return cr, nil
-		}
+	klog.V(1).Infof("Storing %s", req.Key())
+	if err := cs.Write(req.Key(), bs); err != nil {
+		klog.Errorf("unable to write %s: %v", req.Key(), err)
+		return cr, nil
 	}
 
 	cr.Cached = false
 	return cr, nil
 }
 
+// notModified refreshes a previously stored response which the upstream has confirmed is unchanged.
+func notModified(req Request, cs Store, prev Response, r *http.Response) (Response, error) {
+	klog.Infof("NOT MODIFIED[%s]: reusing %d cached bytes", req.Key(), len(prev.Body))
+	cr := prev
+	cr.Header = prev.Header.Clone()
+	cr.MTime = time.Now()
+	cr.Cookies = req.Jar.Cookies(r.Request.URL)
+	// 304 responses carry updated validators and caching headers
+	for _, k := range []string{"ETag", "Last-Modified", "Cache-Control", "Expires", "Date"} {
+		if v := r.Header.Get(k); v != "" {
+			cr.Header.Set(k, v)
+		}
+	}
+
+	bs, err := encode(newEnvelope(req, cr))
+	if err != nil {
+		return cr, fmt.Errorf("encoding %+v: %v", cr, err)
+	}
+	if err := cs.Write(req.Key(), bs); err != nil {
+		klog.Errorf("unable to write %s: %v", req.Key(), err)
+	}
+
+	cr.Cached = true
+	cr.Stale = false
+	cr.Revalidated = true
+	return cr, nil
+}
+
+// Config is how external users configure the cache.
 type Config struct {
+	// MaxAge is the default maximum age of cached content
 	MaxAge time.Duration
+	// Backend is the persistence backend to use: disk, sqlite, mysql, cloudsql, or memory
+	Backend string
+	// Path is where to persist the cache to: a directory for disk, a file for sqlite, or a DSN for mysql
+	Path string
+	// StaleAge is the longest past MaxAge that any request may serve stale content for
+	StaleAge time.Duration
+	// MemoryEntries is the size of the in-memory LRU kept in front of the backend (0 disables)
+	MemoryEntries int
+	// MemoryBytes is the maximum number of value bytes kept in memory (0 is unlimited)
+	MemoryBytes int64
+	// MemoryTTL is how long entries are kept in memory. It defaults to MaxAge plus StaleAge,
+	// as entries evicted any sooner could not be served while stale.
+	MemoryTTL time.Duration
+}
+
+// New returns a new cache store for the configured backend
+func New(c Config) (Store, error) {
+	mc := MemoryConfig{MaxEntries: c.MemoryEntries, MaxBytes: c.MemoryBytes, TTL: c.MemoryTTL}
+	if mc.TTL == 0 {
+		mc.TTL = c.MaxAge + c.StaleAge
+	}
+
+	if c.Backend == "memory" {
+		return configure(NewMemory(mc), c), nil
+	}
+
+	persist, err := newPersistent(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.MemoryEntries == 0 {
+		return configure(persist, c), nil
+	}
+
+	klog.Infof("keeping up to %d entries in memory for %s", c.MemoryEntries, mc.TTL)
+	mc.Persist = persist
+	return configure(NewMemory(mc), c), nil
 }
 
-// New returns a new cache (hardcoded to diskv, for the moment)
-func New(c Config) (*diskv.Diskv, error) {
-	defaultMaxAge = c.MaxAge
-	return initialize()
+// defaults are the options a store was configured with, which apply to each request
+// made through it, and the state those requests share. Options are set once by New,
+// before the store is shared.
+type defaults struct {
+	maxAge time.Duration
+
+	// fetches coalesces concurrent uncached fetches of the same key through this store
+	fetches singleflight.Group
+	// metrics counts problems decoding entries read from this store
+	metrics EntryMetrics
+}
+
+// configurable is implemented by stores which embed defaults
+type configurable interface {
+	MaxAge() time.Duration
+	setDefaults(c Config)
+	inflight() *singleflight.Group
+	entryMetrics() *EntryMetrics
 }
 
-// initialize returns an initialized cache
-func initialize() (*diskv.Diskv, error) {
+// MaxAge returns the maximum age of cached content for requests which do not set one
+func (d *defaults) MaxAge() time.Duration {
+	if d.maxAge > 0 {
+		return d.maxAge
+	}
+	return RecommendedMaxAge
+}
+
+func (d *defaults) setDefaults(c Config) {
+	d.maxAge = c.MaxAge
+}
+
+func (d *defaults) inflight() *singleflight.Group {
+	return &d.fetches
+}
+
+func (d *defaults) entryMetrics() *EntryMetrics {
+	return &d.metrics
+}
+
+// configure applies configured defaults to a store
+func configure(cs Store, c Config) Store {
+	if d, ok := cs.(configurable); ok {
+		d.setDefaults(c)
+		klog.Infof("default expiry is %s", d.MaxAge())
+	}
+	return cs
+}
+
+// newPersistent returns the persistent store for the configured backend
+func newPersistent(c Config) (Store, error) {
+	switch c.Backend {
+	case "", "disk":
+		// Only use the diskv in-memory cache if we are not providing our own
+		var cacheSize uint64
+		if c.MemoryEntries == 0 {
+			cacheSize = 1024 * 1024 * 1024
+		}
+		return newDisk(c.Path, cacheSize)
+	case "sqlite":
+		return newSQLite(c.Path)
+	case "mysql", "cloudsql":
+		if c.Path == "" {
+			return nil, fmt.Errorf("%s backend requires a DSN path", c.Backend)
+		}
+		return NewSQL("mysql", c.Path)
+	default:
+		return nil, fmt.Errorf("unknown cache backend: %q", c.Backend)
+	}
+}
+
+// defaultPath returns the default persistence path within the users cache directory
+func defaultPath(name string) (string, error) {
 	root, err := os.UserCacheDir()
 	if err != nil {
-		return nil, fmt.Errorf("cache dir: %w", err)
+		return "", fmt.Errorf("cache dir: %w", err)
 	}
-	cacheDir := filepath.Join(root, "campwiz")
-	klog.Infof("cache dir is %s", cacheDir)
-	klog.Infof("default expiry is %s", defaultMaxAge)
-
-	return diskv.New(diskv.Options{
-		BasePath:     cacheDir,
-		CacheSizeMax: 1024 * 1024 * 1024,
-	}), nil
+	return filepath.Join(root, name), nil
 }
@@ -1,11 +1,15 @@
 package cache
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/cookiejar"
 	"net/http/httptest"
+	"net/url"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -16,7 +20,7 @@ func TestApplyDefaults(t *testing.T) {
 		URL: "/",
 	}
 
-	got, err := applyDefaults(req)
+	got, err := applyDefaults(req, &FakeStore{})
 	if err != nil {
 		t.Errorf("error: %v", err)
 	}
@@ -39,6 +43,34 @@ func TestApplyDefaults(t *testing.T) {
 	}
 }
 
+func TestConfiguredMaxAge(t *testing.T) {
+	short, err := New(Config{Backend: "memory", MaxAge: time.Minute})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	long, err := New(Config{Backend: "memory", MaxAge: time.Hour})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	for _, tc := range []struct {
+		cs   Store
+		want time.Duration
+	}{
+		{cs: short, want: time.Minute},
+		{cs: long, want: time.Hour},
+		{cs: &FakeStore{}, want: RecommendedMaxAge},
+	} {
+		got, err := applyDefaults(Request{URL: "/"}, tc.cs)
+		if err != nil {
+			t.Fatalf("apply defaults: %v", err)
+		}
+		if got.MaxAge != tc.want {
+			t.Errorf("applyDefaults() with %T MaxAge = %s, want %s", tc.cs, got.MaxAge, tc.want)
+		}
+	}
+}
+
 type FakeStore struct {
 	seen map[string][]byte
 }
@@ -92,3 +124,375 @@ func TestFetch(t *testing.T) {
 		t.Errorf("applyDefaults() mismatch (-want +got):\n%s", diff)
 	}
 }
+
+func TestSignificantKey(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintf(w, "date=%s", r.URL.Query().Get("date"))
+	}))
+	defer ts.Close()
+
+	kf := SignificantKey([]string{"date"}, []string{"region"})
+	cs := &FakeStore{seen: map[string][]byte{}}
+
+	for i, c := range []struct {
+		nonce  string
+		cookie string
+	}{{"1", "a"}, {"2", "b"}, {"3", "c"}} {
+		req := Request{
+			URL:     ts.URL,
+			Form:    url.Values{"date": {"2021-02-12"}, "nonce": {c.nonce}},
+			Cookies: []*http.Cookie{{Name: "region", Value: "west"}, {Name: "session", Value: c.cookie}},
+			KeyFunc: kf,
+		}
+		got, err := Fetch(req, cs)
+		if err != nil {
+			t.Fatalf("fetch error: %v", err)
+		}
+		if got.Cached != (i > 0) {
+			t.Errorf("request %d: got cached=%v", i, got.Cached)
+		}
+	}
+
+	if hits != 1 {
+		t.Errorf("got %d upstream hits for identical searches, want 1", hits)
+	}
+
+	for _, req := range []Request{
+		{URL: ts.URL, Form: url.Values{"date": {"2021-02-13"}}, KeyFunc: kf},
+		{URL: ts.URL, Form: url.Values{"date": {"2021-02-12"}}, Cookies: []*http.Cookie{{Name: "region", Value: "east"}}, KeyFunc: kf},
+	} {
+		got, err := Fetch(req, cs)
+		if err != nil {
+			t.Fatalf("fetch error: %v", err)
+		}
+		if got.Cached {
+			t.Errorf("expected %+v to be a cache miss", req)
+		}
+	}
+}
+
+// age rewrites a cache entry to appear older than it is
+func age(t *testing.T, cs Store, req Request, d time.Duration) {
+	t.Helper()
+	req, err := applyDefaults(req, cs)
+	if err != nil {
+		t.Fatalf("apply defaults: %v", err)
+	}
+	bs, err := cs.Read(req.Key())
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	e, err := decode(bs)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	e.Response.MTime = e.Response.MTime.Add(-d)
+	bs, err = encode(e)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if err := cs.Write(req.Key(), bs); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+}
+
+func TestStaleWhileRevalidate(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+		fmt.Fprintf(w, "version %d", hits)
+	}))
+	defer ts.Close()
+
+	cs := NewMemory(MemoryConfig{})
+	req := Request{URL: ts.URL, MaxAge: time.Hour, StaleAge: time.Hour}
+
+	if _, err := Fetch(req, cs); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	age(t, cs, req, 90*time.Minute)
+
+	got, err := Fetch(req, cs)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !got.Cached || !got.Stale || string(got.Body) != "version 1" {
+		t.Errorf("expected stale version 1, got cached=%v stale=%v body=%q", got.Cached, got.Stale, got.Body)
+	}
+
+	// wait for the background revalidation to complete
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err = Fetch(req, cs)
+		if err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+		if !got.Stale || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got.Stale || !got.Cached || string(got.Body) != "version 2" {
+		t.Errorf("expected fresh version 2, got cached=%v stale=%v body=%q", got.Cached, got.Stale, got.Body)
+	}
+
+	// Beyond the stale window, fetch synchronously
+	age(t, cs, req, 3*time.Hour)
+	got, err = Fetch(req, cs)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got.Cached || got.Stale || string(got.Body) != "version 3" {
+		t.Errorf("expected uncached version 3, got cached=%v stale=%v body=%q", got.Cached, got.Stale, got.Body)
+	}
+}
+
+func TestConditionalRevalidation(t *testing.T) {
+	lastModified := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+
+	tests := []struct {
+		name      string
+		header    string
+		value     string
+		condition string
+	}{
+		{name: "etag", header: "ETag", value: `"v1"`, condition: "If-None-Match"},
+		{name: "last-modified", header: "Last-Modified", value: lastModified, condition: "If-Modified-Since"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			full := 0
+			conditional := 0
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				w.Header().Set(tc.header, tc.value)
+				if r.Header.Get(tc.condition) == tc.value {
+					conditional++
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				full++
+				fmt.Fprint(w, "campgrounds")
+			}))
+			defer ts.Close()
+
+			cs := NewMemory(MemoryConfig{})
+			req := Request{URL: ts.URL, MaxAge: time.Hour}
+			if _, err := Fetch(req, cs); err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			age(t, cs, req, 2*time.Hour)
+
+			got, err := Fetch(req, cs)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if !got.Revalidated || !got.Cached || got.StatusCode != http.StatusOK || string(got.Body) != "campgrounds" {
+				t.Errorf("expected revalidated body, got revalidated=%v cached=%v status=%d body=%q", got.Revalidated, got.Cached, got.StatusCode, got.Body)
+			}
+			if time.Since(got.MTime) > time.Minute {
+				t.Errorf("MTime was not refreshed: %s", got.MTime)
+			}
+
+			// The refreshed entry is now a plain cache hit
+			got, err = Fetch(req, cs)
+			if err != nil {
+				t.Fatalf("fetch: %v", err)
+			}
+			if got.Revalidated || !got.Cached || string(got.Body) != "campgrounds" {
+				t.Errorf("expected cache hit, got revalidated=%v cached=%v body=%q", got.Revalidated, got.Cached, got.Body)
+			}
+
+			if full != 1 || conditional != 1 {
+				t.Errorf("full fetches = %d, conditional fetches = %d, want 1 and 1", full, conditional)
+			}
+		})
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		hits++
+		fmt.Fprintf(w, "version %d", hits)
+	}))
+	defer ts.Close()
+
+	cs := NewMemory(MemoryConfig{})
+	req := Request{URL: ts.URL + "/cached", MaxAge: time.Hour}
+	if _, err := Fetch(req, cs); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	age(t, cs, req, 3*time.Hour)
+
+	tests := []struct {
+		name    string
+		url     string
+		policy  Policy
+		want    string
+		cached  bool
+		wantErr error
+	}{
+		{name: "cache-only expired", url: "/cached", policy: PolicyCacheOnly, want: "version 1", cached: true},
+		{name: "cache-only miss", url: "/uncached", policy: PolicyCacheOnly, wantErr: ErrNotCached},
+		{name: "refresh", url: "/cached", policy: PolicyRefresh, want: "version 2"},
+		{name: "normal", url: "/cached", policy: PolicyNormal, want: "version 2", cached: true},
+		{name: "refresh again", url: "/cached", policy: PolicyRefresh, want: "version 3"},
+		{name: "cache-only fresh", url: "/cached", policy: PolicyCacheOnly, want: "version 3", cached: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Fetch(Request{URL: ts.URL + tc.url, MaxAge: time.Hour, Policy: tc.policy}, cs)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Fetch() error = %v, want %v", err, tc.wantErr)
+			}
+			if string(got.Body) != tc.want || got.Cached != tc.cached {
+				t.Errorf("Fetch() = %q (cached=%v), want %q (cached=%v)", got.Body, got.Cached, tc.want, tc.cached)
+			}
+		})
+	}
+
+	if hits != 3 {
+		t.Errorf("upstream hits = %d, want 3", hits)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for _, s := range []string{"", "normal", "cache-only", "refresh"} {
+		if _, err := ParsePolicy(s); err != nil {
+			t.Errorf("ParsePolicy(%q) error: %v", s, err)
+		}
+	}
+	if _, err := ParsePolicy("offline"); err == nil {
+		t.Errorf("ParsePolicy(offline) expected error")
+	}
+}
+
+func TestFetchCoalescing(t *testing.T) {
+	var mu sync.Mutex
+	hits := 0
+	release := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits++
+		mu.Unlock()
+		<-release
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "shared"})
+		fmt.Fprintln(w, "hi")
+	}))
+	defer ts.Close()
+
+	writes := &countingStore{Store: NewMemory(MemoryConfig{})}
+	cs := NewMemory(MemoryConfig{Persist: writes})
+
+	var wg sync.WaitGroup
+	results := make([]Response, 5)
+	jars := make([]*cookiejar.Jar, len(results))
+	for i := range results {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatalf("jar: %v", err)
+		}
+		jars[i] = jar
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res, err := Fetch(Request{URL: ts.URL, Jar: jars[i]}, cs)
+			if err != nil {
+				t.Errorf("fetch: %v", err)
+			}
+			results[i] = res
+		}(i)
+	}
+
+	// Give every caller a chance to arrive before the upstream responds
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if hits != 1 {
+		t.Errorf("got %d upstream hits, want 1", hits)
+	}
+	if writes.writes != 1 {
+		t.Errorf("got %d cache writes, want 1", writes.writes)
+	}
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for i, res := range results {
+		if string(res.Body) != "hi\n" {
+			t.Errorf("caller %d got body %q", i, res.Body)
+		}
+		if len(jars[i].Cookies(u)) != 1 {
+			t.Errorf("caller %d got cookies %v, want the session cookie", i, jars[i].Cookies(u))
+		}
+	}
+}
+
+// TestFetchSeparateStores verifies that concurrent fetches of the same key through different
+// stores are not coalesced, so that each store is written to
+func TestFetchSeparateStores(t *testing.T) {
+	release := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprintln(w, "hi")
+	}))
+	defer ts.Close()
+
+	stores := []Store{NewMemory(MemoryConfig{}), NewMemory(MemoryConfig{})}
+	var wg sync.WaitGroup
+	for _, cs := range stores {
+		wg.Add(1)
+		go func(cs Store) {
+			defer wg.Done()
+			if _, err := Fetch(Request{URL: ts.URL}, cs); err != nil {
+				t.Errorf("fetch: %v", err)
+			}
+		}(cs)
+	}
+
+	// Give both callers a chance to arrive before the upstream responds
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	for i, cs := range stores {
+		res, err := Fetch(Request{URL: ts.URL, Policy: PolicyCacheOnly}, cs)
+		if err != nil {
+			t.Errorf("store %d was not written to: %v", i, err)
+			continue
+		}
+		if string(res.Body) != "hi\n" {
+			t.Errorf("store %d got body %q", i, res.Body)
+		}
+	}
+}
+
+// countingStore counts writes to a store
+type countingStore struct {
+	Store
+	mu     sync.Mutex
+	writes int
+}
+
+func (c *countingStore) Write(key string, bs []byte) error {
+	c.mu.Lock()
+	c.writes++
+	c.mu.Unlock()
+	return c.Store.Write(key, bs)
+}
@@ -0,0 +1,53 @@
+package cache
+
+import (
+	"github.com/peterbourgon/diskv"
+	"k8s.io/klog/v2"
+)
+
+// DiskStore is a cache store backed by a directory of files
+type DiskStore struct {
+	defaults
+	d *diskv.Diskv
+}
+
+// newDisk returns an initialized diskv cache
+func newDisk(path string, cacheSize uint64) (*DiskStore, error) {
+	if path == "" {
+		p, err := defaultPath("campwiz")
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	klog.Infof("cache dir is %s", path)
+
+	return &DiskStore{d: diskv.New(diskv.Options{
+		BasePath:     path,
+		CacheSizeMax: cacheSize,
+	})}, nil
+}
+
+// Read returns the value stored for a key
+func (s *DiskStore) Read(key string) ([]byte, error) {
+	return s.d.Read(key)
+}
+
+// Write stores a value for a key
+func (s *DiskStore) Write(key string, bs []byte) error {
+	return s.d.Write(key, bs)
+}
+
+// Keys returns all keys within the store
+func (s *DiskStore) Keys() ([]string, error) {
+	var keys []string
+	for k := range s.d.Keys(nil) {
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Delete removes a key from the store
+func (s *DiskStore) Delete(key string) error {
+	return s.d.Erase(key)
+}
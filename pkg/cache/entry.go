@@ -0,0 +1,243 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// Cache entries are stored within a versioned envelope:
+//
+//	magic (3 bytes) | version (1 byte) | flags (1 byte) | crc32 of payload (4 bytes) | payload
+//
+// The payload is a gob-encoded envelope, optionally gzip compressed. Entries without
+// the magic header are raw gob-encoded Responses written by older releases (version 0).
+const (
+	entryVersion = 1
+
+	// flagGzip is set if the payload is gzip compressed
+	flagGzip = 1 << 0
+
+	// compressMin is the payload size at which we begin compressing
+	compressMin = 4096
+
+	headerLen = 9
+)
+
+var (
+	entryMagic = []byte("CWZ")
+
+	// errUnsupportedVersion is returned for entries written by a newer release
+	errUnsupportedVersion = errors.New("unsupported entry version")
+)
+
+// EntryMetrics counts problems encountered while decoding cache entries
+type EntryMetrics struct {
+	// Corrupt entries could not be decoded
+	Corrupt int64
+	// Discarded entries were corrupt and deleted from the store
+	Discarded int64
+	// Migrated entries were rewritten from an older format
+	Migrated int64
+	// Unsupported entries were written by a newer release
+	Unsupported int64
+}
+
+func (m EntryMetrics) String() string {
+	return fmt.Sprintf("%d corrupt, %d discarded, %d migrated, %d unsupported", m.Corrupt, m.Discarded, m.Migrated, m.Unsupported)
+}
+
+// Metrics returns entry decoding metrics for a store, or none for stores which do not keep them
+func Metrics(cs Store) EntryMetrics {
+	if rs, ok := cs.(*recordingStore); ok {
+		cs = rs.Store
+	}
+	d, ok := cs.(configurable)
+	if !ok {
+		return EntryMetrics{}
+	}
+	metrics := d.entryMetrics()
+	return EntryMetrics{
+		Corrupt:     atomic.LoadInt64(&metrics.Corrupt),
+		Discarded:   atomic.LoadInt64(&metrics.Discarded),
+		Migrated:    atomic.LoadInt64(&metrics.Migrated),
+		Unsupported: atomic.LoadInt64(&metrics.Unsupported),
+	}
+}
+
+// RequestSummary describes the request which a cache entry was stored for
+type RequestSummary struct {
+	Method   string
+	URL      string
+	Provider string
+	Key      string
+}
+
+// envelope is what is stored within the cache
+type envelope struct {
+	StoredAt time.Time
+	Request  RequestSummary
+	Response Response
+
+	// version and compressed describe how the entry was stored, and are not themselves stored
+	version    int
+	compressed bool
+}
+
+// newEnvelope returns an envelope for a response to a request
+func newEnvelope(req Request, res Response) envelope {
+	return envelope{
+		StoredAt: time.Now(),
+		Request: RequestSummary{
+			Method:   req.Method,
+			URL:      req.URL,
+			Provider: req.Provider,
+			Key:      req.Key(),
+		},
+		Response: res,
+		version:  entryVersion,
+	}
+}
+
+// encode encodes an envelope for storage in the current format
+func encode(e envelope) ([]byte, error) {
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(&e); err != nil {
+		return nil, fmt.Errorf("gob: %w", err)
+	}
+
+	var flags byte
+	bs := payload.Bytes()
+	if len(bs) >= compressMin {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(bs); err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("gzip close: %w", err)
+		}
+		bs = buf.Bytes()
+		flags |= flagGzip
+	}
+
+	out := make([]byte, headerLen, headerLen+len(bs))
+	copy(out, entryMagic)
+	out[3] = entryVersion
+	out[4] = flags
+	binary.BigEndian.PutUint32(out[5:], crc32.ChecksumIEEE(bs))
+	return append(out, bs...), nil
+}
+
+// decode decodes a stored entry, in the current or a previous format
+func decode(bs []byte) (envelope, error) {
+	if !bytes.HasPrefix(bs, entryMagic) {
+		return decodeLegacy(bs)
+	}
+
+	if len(bs) < headerLen {
+		return envelope{}, fmt.Errorf("truncated header (%d bytes)", len(bs))
+	}
+
+	version := int(bs[3])
+	if version > entryVersion {
+		return envelope{}, fmt.Errorf("version %d: %w", version, errUnsupportedVersion)
+	}
+
+	flags := bs[4]
+	payload := bs[headerLen:]
+	if sum := crc32.ChecksumIEEE(payload); sum != binary.BigEndian.Uint32(bs[5:]) {
+		return envelope{}, fmt.Errorf("checksum mismatch: got %x, want %x", sum, binary.BigEndian.Uint32(bs[5:]))
+	}
+
+	if flags&flagGzip != 0 {
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return envelope{}, fmt.Errorf("gzip: %w", err)
+		}
+		payload, err = ioutil.ReadAll(zr)
+		if err != nil {
+			return envelope{}, fmt.Errorf("gzip read: %w", err)
+		}
+	}
+
+	var e envelope
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&e); err != nil {
+		return envelope{}, fmt.Errorf("gob: %w", err)
+	}
+	e.version = version
+	e.compressed = flags&flagGzip != 0
+	return e, nil
+}
+
+// decodeLegacy decodes a version 0 entry, which is a raw gob-encoded Response
+func decodeLegacy(bs []byte) (envelope, error) {
+	var res Response
+	if err := gob.NewDecoder(bytes.NewReader(bs)).Decode(&res); err != nil {
+		return envelope{}, fmt.Errorf("legacy gob: %w", err)
+	}
+	return envelope{
+		StoredAt: res.MTime,
+		Request:  RequestSummary{URL: res.URL, Provider: res.Provider},
+		Response: res,
+		version:  0,
+	}, nil
+}
+
+// readEntry reads an entry from the store, migrating or discarding entries as necessary
+func readEntry(cs Store, key string) (envelope, error) {
+	bs, err := cs.Read(key)
+	if err != nil {
+		return envelope{}, err
+	}
+
+	metrics := &EntryMetrics{}
+	if d, ok := cs.(configurable); ok {
+		metrics = d.entryMetrics()
+	}
+
+	e, err := decode(bs)
+	if errors.Is(err, errUnsupportedVersion) {
+		// Possibly written by a newer release sharing this store, so leave it be.
+		atomic.AddInt64(&metrics.Unsupported, 1)
+		return e, err
+	}
+
+	if err != nil {
+		atomic.AddInt64(&metrics.Corrupt, 1)
+		klog.Warningf("corrupt cache entry %s: %v", key, err)
+		if l, ok := cs.(Lister); ok {
+			if derr := l.Delete(key); derr != nil {
+				klog.Errorf("unable to discard %s: %v", key, derr)
+			} else {
+				atomic.AddInt64(&metrics.Discarded, 1)
+			}
+		}
+		return e, err
+	}
+
+	if e.version < entryVersion {
+		klog.V(1).Infof("migrating %s from version %d to %d", key, e.version, entryVersion)
+		e.Request.Key = key
+		nbs, err := encode(e)
+		if err != nil {
+			return e, fmt.Errorf("encode: %w", err)
+		}
+		if err := cs.Write(key, nbs); err != nil {
+			klog.Errorf("unable to migrate %s: %v", key, err)
+		} else {
+			atomic.AddInt64(&metrics.Migrated, 1)
+		}
+	}
+
+	return e, nil
+}
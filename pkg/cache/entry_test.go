@@ -0,0 +1,142 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		compressed bool
+	}{
+		{"small", "hi", false},
+		{"large", strings.Repeat("<tr><td>campsite</td></tr>", 1000), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Method: "GET", URL: "http://example.com/", Provider: "test"}
+			res := Response{URL: req.URL, StatusCode: 200, Body: []byte(tt.body), MTime: time.Now().Round(0)}
+
+			bs, err := encode(newEnvelope(req, res))
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+
+			if tt.compressed && len(bs) > len(tt.body)/2 {
+				t.Errorf("expected compression, got %d bytes for %d byte body", len(bs), len(tt.body))
+			}
+
+			got, err := decode(bs)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.version != entryVersion || got.compressed != tt.compressed {
+				t.Errorf("got version=%d compressed=%v, want %d %v", got.version, got.compressed, entryVersion, tt.compressed)
+			}
+			if got.Request.Provider != "test" || got.Request.Key != req.Key() {
+				t.Errorf("unexpected request summary: %+v", got.Request)
+			}
+			if diff := cmp.Diff(res, got.Response); diff != "" {
+				t.Errorf("decode() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	bs, err := encode(newEnvelope(Request{URL: "http://example.com/"}, Response{Body: []byte("hi")}))
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	flipped := append([]byte{}, bs...)
+	flipped[len(flipped)-1] ^= 0xff
+	future := append([]byte{}, bs...)
+	future[3] = entryVersion + 1
+
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", []byte{}},
+		{"garbage", []byte("not a gob")},
+		{"truncated", bs[:5]},
+		{"checksum", flipped},
+		{"future", future},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decode(tt.in); err == nil {
+				t.Errorf("expected error decoding %q", tt.in)
+			}
+		})
+	}
+
+	if _, err := decode(future); !errors.Is(err, errUnsupportedVersion) {
+		t.Errorf("got %v, want errUnsupportedVersion", err)
+	}
+}
+
+func TestReadEntryMigratesAndDiscards(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintln(w, "fresh")
+	}))
+	defer ts.Close()
+
+	cs := NewMemory(MemoryConfig{})
+	req, err := applyDefaults(Request{URL: ts.URL}, cs)
+	if err != nil {
+		t.Fatalf("apply defaults: %v", err)
+	}
+
+	// An entry written by an older release
+	var legacy bytes.Buffer
+	if err := gob.NewEncoder(&legacy).Encode(&Response{URL: ts.URL, StatusCode: 200, Body: []byte("legacy"), MTime: time.Now()}); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	cs.Write(req.Key(), legacy.Bytes())
+
+	before := Metrics(cs)
+	got, err := Fetch(req, cs)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if !got.Cached || string(got.Body) != "legacy" {
+		t.Errorf("expected cached legacy entry, got cached=%v body=%q", got.Cached, got.Body)
+	}
+
+	e := Inspect(cs, req.Key())
+	if e.Err != nil || e.Version != entryVersion {
+		t.Errorf("expected entry to be migrated to version %d, got %d (err=%v)", entryVersion, e.Version, e.Err)
+	}
+
+	// A corrupt entry should be a miss, and be discarded
+	cs.Write(req.Key(), []byte("CWZ\x01\x00garbage"))
+	got, err = Fetch(req, cs)
+	if err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+	if got.Cached || string(got.Body) != "fresh\n" || hits != 1 {
+		t.Errorf("expected uncached fetch, got cached=%v body=%q hits=%d", got.Cached, got.Body, hits)
+	}
+
+	after := Metrics(cs)
+	if after.Migrated-before.Migrated != 1 || after.Corrupt-before.Corrupt != 1 || after.Discarded-before.Discarded != 1 {
+		t.Errorf("unexpected metrics: before=%s after=%s", before, after)
+	}
+}
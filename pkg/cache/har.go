@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"k8s.io/klog/v2"
+)
+
+// Recorder captures fetches in the HTTP Archive (HAR) 1.2 format, which can be opened
+// within browser developer tools to debug a misbehaving backend.
+type Recorder struct {
+	mu      sync.Mutex
+	entries []harEntry
+}
+
+// NewRecorder returns an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// recordingStore is a Store whose fetches are recorded by Fetch
+type recordingStore struct {
+	Store
+	rec *Recorder
+}
+
+// Record returns a Store which records all fetches made through it into rec
+func Record(cs Store, rec *Recorder) Store {
+	return &recordingStore{Store: cs, rec: rec}
+}
+
+// Len returns the number of recorded fetches
+func (r *Recorder) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Encode writes the recorded fetches as a HAR document
+func (r *Recorder) Encode(w io.Writer) error {
+	r.mu.Lock()
+	h := har{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "campwiz", Version: "1"},
+		Entries: append([]harEntry{}, r.entries...),
+	}}
+	r.mu.Unlock()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(h)
+}
+
+// WriteFile writes the recorded fetches as a HAR file
+func (r *Recorder) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	if err := r.Encode(f); err != nil {
+		f.Close()
+		return fmt.Errorf("write: %w", err)
+	}
+	return f.Close()
+}
+
+// cacheStatus describes how a fetch was served
+func cacheStatus(res Response, err error) string {
+	switch {
+	case err != nil:
+		return "error"
+	case res.Revalidated:
+		return "revalidated"
+	case res.Stale:
+		return "stale"
+	case res.Cached:
+		return "hit"
+	default:
+		return "miss"
+	}
+}
+
+// add records a completed fetch
+func (r *Recorder) add(req Request, res Response, err error, start time.Time) {
+	ms := float64(time.Since(start)) / float64(time.Millisecond)
+	e := harEntry{
+		StartedDateTime: start.Format(time.RFC3339Nano),
+		Time:            ms,
+		Cache:           struct{}{},
+		Timings:         harTimings{Send: 0, Wait: ms, Receive: 0},
+		CacheStatus:     cacheStatus(res, err),
+		Provider:        req.Provider,
+		Key:             req.Key(),
+	}
+
+	hr, herr := newHTTPRequest(req, nil)
+	if herr != nil {
+		klog.Errorf("unable to record %s: %v", req.URL, herr)
+		return
+	}
+
+	e.Request = harRequest{
+		Method:      hr.Method,
+		URL:         hr.URL.String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     harCookies(req.Cookies),
+		Headers:     harNameValues(hr.Header),
+		QueryString: harNameValues(hr.URL.Query()),
+		HeadersSize: -1,
+		BodySize:    len(req.Body),
+	}
+	if len(req.Body) > 0 {
+		e.Request.PostData = &harPostData{MimeType: hr.Header.Get("Content-Type"), Text: string(req.Body)}
+	}
+
+	e.Response = harResponse{
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     harCookies(res.Cookies),
+		Headers:     harNameValues(res.Header),
+		Content:     harContent{Size: len(res.Body), MimeType: res.Header.Get("Content-Type")},
+		HeadersSize: -1,
+		BodySize:    len(res.Body),
+	}
+	if res.Cached {
+		// Nothing was transferred over the network
+		e.Response.BodySize = 0
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	if utf8.Valid(res.Body) {
+		e.Response.Content.Text = string(res.Body)
+	} else {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(res.Body)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, e)
+	r.mu.Unlock()
+}
+
+// harNameValues converts headers or query values to sorted name/value pairs
+func harNameValues(m map[string][]string) []harNameValue {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	nvs := []harNameValue{}
+	for _, k := range keys {
+		for _, v := range m[k] {
+			nvs = append(nvs, harNameValue{Name: k, Value: v})
+		}
+	}
+	return nvs
+}
+
+func harCookies(cs []*http.Cookie) []harNameValue {
+	nvs := []harNameValue{}
+	for _, c := range cs {
+		nvs = append(nvs, harNameValue{Name: c.Name, Value: c.Value})
+	}
+	return nvs
+}
+
+// The HAR 1.2 format: http://www.softwareishard.com/blog/har-12-spec/
+// Fields beginning with an underscore are custom to campwiz.
+type har struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+
+	CacheStatus string `json:"_cacheStatus"`
+	Provider    string `json:"_provider,omitempty"`
+	Key         string `json:"_key"`
+	Error       string `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
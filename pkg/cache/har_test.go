@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecorder(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<p>%s</p>", r.URL.Query().Get("q"))
+	}))
+	defer ts.Close()
+
+	// A server which is no longer listening
+	gone := httptest.NewServer(http.NotFoundHandler())
+	gone.Close()
+
+	rec := NewRecorder()
+	cs := Record(NewMemory(MemoryConfig{}), rec)
+
+	req := Request{URL: ts.URL, Provider: "test", Form: url.Values{"q": []string{"camp"}}}
+	for i := 0; i < 2; i++ {
+		if _, err := Fetch(req, cs); err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+	}
+	if _, err := Fetch(Request{URL: gone.URL}, cs); err == nil {
+		t.Errorf("expected error fetching from %s", gone.URL)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.Encode(&buf); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+
+	var got har
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, buf.String())
+	}
+
+	type summary struct {
+		Status   string
+		Method   string
+		URL      string
+		Code     int
+		Body     string
+		Provider string
+	}
+	var sums []summary
+	for _, e := range got.Log.Entries {
+		sums = append(sums, summary{
+			Status:   e.CacheStatus,
+			Method:   e.Request.Method,
+			URL:      e.Request.URL,
+			Code:     e.Response.Status,
+			Body:     e.Response.Content.Text,
+			Provider: e.Provider,
+		})
+	}
+
+	want := []summary{
+		{Status: "miss", Method: "GET", URL: ts.URL + "?q=camp", Code: 200, Body: "<p>camp</p>", Provider: "test"},
+		{Status: "hit", Method: "GET", URL: ts.URL + "?q=camp", Code: 200, Body: "<p>camp</p>", Provider: "test"},
+		{Status: "error", Method: "GET", URL: gone.URL},
+	}
+	if diff := cmp.Diff(want, sums); diff != "" {
+		t.Errorf("entries mismatch (-want +got):\n%s", diff)
+	}
+
+	if got.Log.Version != "1.2" {
+		t.Errorf("version = %q, want 1.2", got.Log.Version)
+	}
+	if got.Log.Entries[2].Error == "" {
+		t.Errorf("expected error to be recorded: %+v", got.Log.Entries[2])
+	}
+
+	path := filepath.Join(t.TempDir(), "campwiz.har")
+	if err := rec.WriteFile(path); err != nil {
+		t.Errorf("write file: %v", err)
+	}
+}
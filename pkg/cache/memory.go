@@ -0,0 +1,237 @@
+package cache
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// MemoryConfig configures an in-memory store
+type MemoryConfig struct {
+	// MaxEntries is the maximum number of entries to keep (0 is unlimited)
+	MaxEntries int
+	// MaxBytes is the maximum number of value bytes to keep (0 is unlimited)
+	MaxBytes int64
+	// TTL is how long an entry may be kept after it was written (0 is forever)
+	TTL time.Duration
+	// Persist is an optional store that writes are passed through to, and misses are read from
+	Persist Store
+}
+
+// Stats describes the current state of an in-memory store
+type Stats struct {
+	Entries   int
+	Bytes     int64
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Expired   int64
+}
+
+// HitRate returns the fraction of reads that were served from memory
+func (s Stats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("%d entries, %d bytes, %.1f%% hit rate (%d hits, %d misses), %d evicted, %d expired",
+		s.Entries, s.Bytes, s.HitRate()*100, s.Hits, s.Misses, s.Evictions, s.Expired)
+}
+
+// memEntry is a single cached value
+type memEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+
+	// position within the recency and expiry lists
+	recent *list.Element
+	expiry *list.Element
+}
+
+// MemoryStore is a bounded LRU store with TTL-based eviction
+type MemoryStore struct {
+	defaults
+	c     MemoryConfig
+	mu    sync.Mutex
+	items map[string]*memEntry
+	// recent is ordered from most to least recently used
+	recent *list.List
+	// expiry is ordered by write time, which is also expiry order as the TTL is fixed
+	expiry *list.List
+	stats  Stats
+
+	// now is swappable for testing
+	now func() time.Time
+}
+
+// NewMemory returns an in-memory LRU store
+func NewMemory(c MemoryConfig) *MemoryStore {
+	return &MemoryStore{
+		c:      c,
+		items:  map[string]*memEntry{},
+		recent: list.New(),
+		expiry: list.New(),
+		now:    time.Now,
+	}
+}
+
+// Read returns a value from memory, falling back to the persistent store
+func (m *MemoryStore) Read(key string) ([]byte, error) {
+	m.mu.Lock()
+	m.expire()
+	if e, ok := m.items[key]; ok {
+		m.recent.MoveToFront(e.recent)
+		m.stats.Hits++
+		m.mu.Unlock()
+		return e.value, nil
+	}
+	m.stats.Misses++
+	m.mu.Unlock()
+
+	if m.c.Persist == nil {
+		return nil, fmt.Errorf("%q not found", key)
+	}
+
+	bs, err := m.c.Persist.Read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.add(key, bs)
+	m.mu.Unlock()
+	return bs, nil
+}
+
+// Peek returns a value from memory or the persistent store, leaving the LRU and its stats untouched
+func (m *MemoryStore) Peek(key string) ([]byte, error) {
+	m.mu.Lock()
+	m.expire()
+	if e, ok := m.items[key]; ok {
+		m.mu.Unlock()
+		return e.value, nil
+	}
+	m.mu.Unlock()
+
+	if m.c.Persist == nil {
+		return nil, fmt.Errorf("%q not found", key)
+	}
+	return m.c.Persist.Read(key)
+}
+
+// Write stores a value in memory, and writes it through to the persistent store
+func (m *MemoryStore) Write(key string, bs []byte) error {
+	m.mu.Lock()
+	m.expire()
+	m.add(key, bs)
+	m.mu.Unlock()
+
+	if m.c.Persist == nil {
+		return nil
+	}
+	return m.c.Persist.Write(key, bs)
+}
+
+// Keys returns all keys, preferring the persistent store if it can be listed
+func (m *MemoryStore) Keys() ([]string, error) {
+	if l, ok := m.c.Persist.(Lister); ok {
+		return l.Keys()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+
+	keys := []string{}
+	for e := m.recent.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*memEntry).key)
+	}
+	return keys, nil
+}
+
+// Delete removes a key from memory and the persistent store
+func (m *MemoryStore) Delete(key string) error {
+	m.mu.Lock()
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+	m.mu.Unlock()
+
+	if l, ok := m.c.Persist.(Lister); ok {
+		return l.Delete(key)
+	}
+	return nil
+}
+
+// Stats returns statistics for the in-memory store
+func (m *MemoryStore) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expire()
+	return m.stats
+}
+
+// add inserts or replaces an entry, evicting the least recently used entries to make room.
+func (m *MemoryStore) add(key string, bs []byte) {
+	if e, ok := m.items[key]; ok {
+		m.remove(e)
+	}
+
+	e := &memEntry{key: key, value: bs}
+	if m.c.TTL > 0 {
+		e.expires = m.now().Add(m.c.TTL)
+	}
+	e.recent = m.recent.PushFront(e)
+	e.expiry = m.expiry.PushBack(e)
+	m.items[key] = e
+	m.stats.Entries++
+	m.stats.Bytes += int64(len(bs))
+
+	for m.recent.Len() > 1 && m.full() {
+		oldest := m.recent.Back().Value.(*memEntry)
+		klog.V(2).Infof("evicting %s", oldest.key)
+		m.remove(oldest)
+		m.stats.Evictions++
+	}
+}
+
+// full returns true if the store is over one of its limits
+func (m *MemoryStore) full() bool {
+	if m.c.MaxEntries > 0 && m.stats.Entries > m.c.MaxEntries {
+		return true
+	}
+	return m.c.MaxBytes > 0 && m.stats.Bytes > m.c.MaxBytes
+}
+
+// expire removes entries which have outlived the TTL
+func (m *MemoryStore) expire() {
+	if m.c.TTL == 0 {
+		return
+	}
+	now := m.now()
+	for m.expiry.Len() > 0 {
+		e := m.expiry.Front().Value.(*memEntry)
+		if now.Before(e.expires) {
+			return
+		}
+		klog.V(2).Infof("expiring %s", e.key)
+		m.remove(e)
+		m.stats.Expired++
+	}
+}
+
+// remove removes an entry from all indexes
+func (m *MemoryStore) remove(e *memEntry) {
+	m.recent.Remove(e.recent)
+	m.expiry.Remove(e.expiry)
+	delete(m.items, e.key)
+	m.stats.Entries--
+	m.stats.Bytes -= int64(len(e.value))
+}
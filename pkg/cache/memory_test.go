@@ -0,0 +1,168 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMemoryLRU(t *testing.T) {
+	m := NewMemory(MemoryConfig{MaxEntries: 2})
+
+	for _, k := range []string{"a", "b"} {
+		if err := m.Write(k, []byte(k)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	// make "a" the most recently used, so that "b" is evicted
+	if _, err := m.Read("a"); err != nil {
+		t.Fatalf("read a: %v", err)
+	}
+	if err := m.Write("c", []byte("c")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	if _, err := m.Read("b"); err == nil {
+		t.Errorf("expected b to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		got, err := m.Read(k)
+		if err != nil {
+			t.Fatalf("read %s: %v", k, err)
+		}
+		if string(got) != k {
+			t.Errorf("got %q, want %q", got, k)
+		}
+	}
+
+	st := m.Stats()
+	if st.Entries != 2 || st.Evictions != 1 || st.Hits != 3 || st.Misses != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+	if st.HitRate() != 0.75 {
+		t.Errorf("got hit rate %f, want 0.75", st.HitRate())
+	}
+}
+
+func TestMemoryMaxBytes(t *testing.T) {
+	m := NewMemory(MemoryConfig{MaxBytes: 10})
+	m.Write("a", []byte("12345"))
+	m.Write("b", []byte("12345"))
+	m.Write("c", []byte("1"))
+
+	st := m.Stats()
+	if st.Entries != 2 || st.Bytes != 6 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestMemoryTTL(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(MemoryConfig{TTL: time.Minute})
+	m.now = func() time.Time { return now }
+
+	m.Write("old", []byte("old"))
+	now = now.Add(30 * time.Second)
+	m.Write("new", []byte("new"))
+	now = now.Add(45 * time.Second)
+
+	if _, err := m.Read("old"); err == nil {
+		t.Errorf("expected old to have expired")
+	}
+	if _, err := m.Read("new"); err != nil {
+		t.Errorf("expected new to be present: %v", err)
+	}
+
+	st := m.Stats()
+	if st.Entries != 1 || st.Expired != 1 || st.Bytes != 3 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestMemoryWriteThrough(t *testing.T) {
+	persist := &FakeStore{seen: map[string][]byte{}}
+	m := NewMemory(MemoryConfig{MaxEntries: 1, Persist: persist})
+
+	m.Write("a", []byte("a"))
+	m.Write("b", []byte("b"))
+
+	if string(persist.seen["a"]) != "a" || string(persist.seen["b"]) != "b" {
+		t.Errorf("expected writes to pass through: %v", persist.seen)
+	}
+
+	// "a" was evicted from memory, but should be read back from the persistent store
+	got, err := m.Read("a")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "a" {
+		t.Errorf("got %q, want %q", got, "a")
+	}
+
+	st := m.Stats()
+	if st.Misses != 1 || st.Entries != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+func TestMemoryFetch(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintln(w, "hi")
+	}))
+	defer ts.Close()
+
+	m := NewMemory(MemoryConfig{MaxEntries: 10, TTL: time.Hour})
+	for i := 0; i < 3; i++ {
+		if _, err := Fetch(Request{URL: ts.URL}, m); err != nil {
+			t.Fatalf("fetch: %v", err)
+		}
+	}
+
+	if hits != 1 {
+		t.Errorf("got %d upstream hits, want 1", hits)
+	}
+	if st := m.Stats(); st.Hits != 2 || st.Misses != 1 {
+		t.Errorf("unexpected stats: %+v", st)
+	}
+}
+
+// TestNewMemoryConfig verifies that memory entries outlive MaxAge for long enough to be served while stale
+func TestNewMemoryConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Config
+		want MemoryConfig
+	}{
+		{
+			name: "defaults",
+			in:   Config{Backend: "memory", MaxAge: time.Hour, StaleAge: 6 * time.Hour},
+			want: MemoryConfig{TTL: 7 * time.Hour},
+		},
+		{
+			name: "explicit",
+			in:   Config{Backend: "memory", MaxAge: time.Hour, StaleAge: 6 * time.Hour, MemoryEntries: 5, MemoryBytes: 1024, MemoryTTL: time.Minute},
+			want: MemoryConfig{MaxEntries: 5, MaxBytes: 1024, TTL: time.Minute},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cs, err := New(tc.in)
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+			ms, ok := cs.(*MemoryStore)
+			if !ok {
+				t.Fatalf("got %T, want *MemoryStore", cs)
+			}
+			if ms.c != tc.want {
+				t.Errorf("got config %+v, want %+v", ms.c, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	// database/sql drivers for the supported persistence backends
+	_ "github.com/go-sql-driver/mysql"
+	_ "modernc.org/sqlite"
+
+	"k8s.io/klog/v2"
+)
+
+// SQLStore is a cache store backed by any database/sql driver which supports REPLACE INTO.
+type SQLStore struct {
+	defaults
+	db *sql.DB
+}
+
+// NewSQL returns a store backed by a database/sql driver
+func NewSQL(driver string, dsn string) (*SQLStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", driver, err)
+	}
+
+	// VARCHAR and LONGBLOB are understood by both SQLite and MySQL
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS cache (
+		k VARCHAR(255) NOT NULL PRIMARY KEY,
+		v LONGBLOB
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create table: %w", err)
+	}
+
+	return &SQLStore{db: db}, nil
+}
+
+// newSQLite returns a SQL store backed by an embedded SQLite file
+func newSQLite(path string) (*SQLStore, error) {
+	if path == "" {
+		p, err := defaultPath("campwiz.db")
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("mkdir: %w", err)
+	}
+
+	klog.Infof("cache db is %s", path)
+	// Writers from other processes wait for their turn rather than failing with "database is locked",
+	// and the write-ahead log lets them read while another writes.
+	s, err := NewSQL("sqlite", path+"?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows only one writer at a time, so writers within this process share a connection
+	s.db.SetMaxOpenConns(1)
+	return s, nil
+}
+
+// Read returns the value stored for a key
+func (s *SQLStore) Read(key string) ([]byte, error) {
+	var bs []byte
+	err := s.db.QueryRow(`SELECT v FROM cache WHERE k = ?`, key).Scan(&bs)
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", key, err)
+	}
+	return bs, nil
+}
+
+// Write stores a value for a key, replacing any previous value
+func (s *SQLStore) Write(key string, bs []byte) error {
+	_, err := s.db.Exec(`REPLACE INTO cache (k, v) VALUES (?, ?)`, key, bs)
+	if err != nil {
+		return fmt.Errorf("write %q: %w", key, err)
+	}
+	return nil
+}
+
+// Close closes the underlying database
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Keys returns all keys within the store
+func (s *SQLStore) Keys() ([]string, error) {
+	rows, err := s.db.Query(`SELECT k FROM cache ORDER BY k`)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return keys, fmt.Errorf("scan: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// Delete removes a key from the store
+func (s *SQLStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM cache WHERE k = ?`, key)
+	if err != nil {
+		return fmt.Errorf("delete %q: %w", key, err)
+	}
+	return nil
+}
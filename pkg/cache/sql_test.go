@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	cs, err := New(Config{Backend: "sqlite", Path: filepath.Join(t.TempDir(), "cache.db"), MaxAge: RecommendedMaxAge})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	if _, err := cs.Read("missing"); err == nil {
+		t.Errorf("expected error reading missing key")
+	}
+
+	if err := cs.Write("key", []byte("one")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := cs.Write("key", []byte("two")); err != nil {
+		t.Fatalf("overwrite: %v", err)
+	}
+
+	got, err := cs.Read("key")
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(got) != "two" {
+		t.Errorf("got %q, want %q", got, "two")
+	}
+}
+
+// TestSQLiteConcurrentWrites verifies that concurrent writers, such as searches and background
+// revalidation, or cw and the server sharing a file, do not fail with "database is locked"
+func TestSQLiteConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.db")
+	var stores []Store
+	for i := 0; i < 2; i++ {
+		cs, err := New(Config{Backend: "sqlite", Path: path, MaxAge: RecommendedMaxAge})
+		if err != nil {
+			t.Fatalf("new: %v", err)
+		}
+		stores = append(stores, cs)
+	}
+
+	const workers = 16
+	const writes = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*writes)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cs := stores[i%len(stores)]
+			for j := 0; j < writes; j++ {
+				key := fmt.Sprintf("key-%d-%d", i, j)
+				if err := cs.Write(key, []byte(key)); err != nil {
+					errs <- err
+					continue
+				}
+				if _, err := cs.Read(key); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent write: %v", err)
+	}
+
+	keys, err := stores[0].(Lister).Keys()
+	if err != nil {
+		t.Fatalf("keys: %v", err)
+	}
+	if len(keys) != workers*writes {
+		t.Errorf("got %d keys, want %d", len(keys), workers*writes)
+	}
+}
+
+func TestSQLiteFetch(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		fmt.Fprintln(w, "hi")
+	}))
+	defer ts.Close()
+
+	cs, err := New(Config{Backend: "sqlite", Path: filepath.Join(t.TempDir(), "cache.db"), MaxAge: RecommendedMaxAge})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := Fetch(Request{URL: ts.URL}, cs)
+		if err != nil {
+			t.Fatalf("fetch error: %v", err)
+		}
+		if string(got.Body) != "hi\n" {
+			t.Errorf("got response: %q", got.Body)
+		}
+	}
+
+	if hits != 1 {
+		t.Errorf("got %d upstream hits, want 1", hits)
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	if _, err := New(Config{Backend: "floppy"}); err == nil {
+		t.Errorf("expected error for unknown backend")
+	}
+	if _, err := New(Config{Backend: "mysql"}); err == nil {
+		t.Errorf("expected error for mysql without a DSN")
+	}
+}
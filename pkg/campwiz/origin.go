@@ -0,0 +1,64 @@
+package campwiz
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DistanceMax filters and ranks results by the distance of the furthest origin
+	DistanceMax = "max"
+	// DistanceSum filters and ranks results by the total distance travelled from every origin
+	DistanceSum = "sum"
+)
+
+// Origin is where a party travels from
+type Origin struct {
+	Name string
+	Lat  float64
+	Lon  float64
+}
+
+// String returns the origin in the form accepted by ParseOrigin
+func (o Origin) String() string {
+	return fmt.Sprintf("%s=%.4f,%.4f", o.Name, o.Lat, o.Lon)
+}
+
+// ParseOrigin parses an origin such as "Sacramento=38.58,-121.49". The name is optional.
+func ParseOrigin(s string) (Origin, error) {
+	o := Origin{}
+	coords := s
+	if i := strings.LastIndex(s, "="); i >= 0 {
+		o.Name = strings.TrimSpace(s[:i])
+		coords = s[i+1:]
+	}
+
+	parts := strings.Split(coords, ",")
+	if len(parts) != 2 {
+		return o, fmt.Errorf("origin %q: want [name=]latitude,longitude", s)
+	}
+
+	var err error
+	if o.Lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil || o.Lat < -90 || o.Lat > 90 {
+		return o, fmt.Errorf("origin %q: invalid latitude %q", s, parts[0])
+	}
+	if o.Lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil || o.Lon < -180 || o.Lon > 180 {
+		return o, fmt.Errorf("origin %q: invalid longitude %q", s, parts[1])
+	}
+	if o.Name == "" {
+		o.Name = fmt.Sprintf("%.2f,%.2f", o.Lat, o.Lon)
+	}
+	return o, nil
+}
+
+// ParseOriginDistance validates how distances from multiple origins are combined, defaulting to DistanceMax
+func ParseOriginDistance(s string) (string, error) {
+	switch s {
+	case "", DistanceMax:
+		return DistanceMax, nil
+	case DistanceSum:
+		return DistanceSum, nil
+	}
+	return "", fmt.Errorf("unknown origin distance %q: want %q or %q", s, DistanceMax, DistanceSum)
+}
@@ -0,0 +1,42 @@
+package campwiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseOrigin(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Origin
+		wantErr bool
+	}{
+		{in: "Sacramento=38.58,-121.49", want: Origin{Name: "Sacramento", Lat: 38.58, Lon: -121.49}},
+		{in: " San Francisco = 37.77, -122.42 ", want: Origin{Name: "San Francisco", Lat: 37.77, Lon: -122.42}},
+		{in: "37.77,-122.42", want: Origin{Name: "37.77,-122.42", Lat: 37.77, Lon: -122.42}},
+		{in: "Sacramento", wantErr: true},
+		{in: "Sacramento=38.58", wantErr: true},
+		{in: "Sacramento=north,-121.49", wantErr: true},
+		{in: "Sacramento=98.58,-121.49", wantErr: true},
+		{in: "Sacramento=38.58,-221.49", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseOrigin(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseOrigin(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseOrigin(%q) unexpected diff (-want +got):\n%s", tc.in, diff)
+			}
+			if rt, err := ParseOrigin(got.String()); err != nil || rt != got {
+				t.Errorf("ParseOrigin(%q) = %+v, %v; want it to round-trip", got.String(), rt, err)
+			}
+		})
+	}
+}
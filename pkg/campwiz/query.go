@@ -1,6 +1,39 @@
 package campwiz
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+// FetchPolicy controls whether providers may be queried from the cache, the network, or both
+type FetchPolicy string
+
+const (
+	// FetchNormal uses cached results until they expire
+	FetchNormal FetchPolicy = "normal"
+	// FetchCacheOnly uses cached results regardless of age, and never queries providers directly
+	FetchCacheOnly FetchPolicy = "cache-only"
+	// FetchRefresh ignores cached results, querying providers directly
+	FetchRefresh FetchPolicy = "refresh"
+)
+
+// FetchPolicies are the available fetch policies
+var FetchPolicies = []FetchPolicy{FetchNormal, FetchCacheOnly, FetchRefresh}
+
+// ParseFetchPolicy parses a fetch policy name, defaulting to FetchNormal
+func ParseFetchPolicy(s string) (FetchPolicy, error) {
+	if s == "" {
+		return FetchNormal, nil
+	}
+	for _, p := range FetchPolicies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("unknown fetch policy %q, expected one of %v", s, FetchPolicies)
+}
 
 // Query defines a list of attributes that can be sent to the camp engines
 type Query struct {
@@ -12,6 +45,37 @@ type Query struct {
 	MinRating   float64
 	Keywords    []string
 
-	SiteKinds []int
-	Features  []int
+	// MinRatingExclusive excludes results rated exactly MinRating, as in "rating>7"
+	MinRatingExclusive bool
+
+	// Origins are where each party of a group trip travels from. If set, Lat and Lon are ignored.
+	Origins []Origin
+	// OriginDistance is how the distances from each origin are combined: DistanceMax or DistanceSum
+	OriginDistance string
+
+	// Route is a path of waypoints to search along, rather than around a location
+	Route []geo.Point
+	// MaxDetour is how far from the route results may be, in miles. MaxDistance is ignored along a route.
+	MaxDetour int
+
+	// MaxDriveTime is the longest drive to results, from the furthest origin or all origins combined
+	// like MaxDistance. It is ignored along a route.
+	MaxDriveTime time.Duration
+
+	// Sort is the name of the ranking strategy to order results by
+	Sort string
+	// SortWeights are the signal weights for the composite ranking strategy
+	SortWeights map[string]float64
+
+	// ServeStale permits slightly stale cached pages, which are refreshed in the background
+	ServeStale bool
+	// Policy controls whether providers may be queried from the cache, the network, or both
+	Policy FetchPolicy
+
+	// SiteKinds limits availability to these kinds of site, if set
+	SiteKinds []SiteKind
+	// ExcludeKinds are kinds of site whose availability is not wanted
+	ExcludeKinds []SiteKind
+
+	Features []int
 }
@@ -0,0 +1,14 @@
+package campwiz
+
+import "testing"
+
+func TestParseFetchPolicy(t *testing.T) {
+	for _, s := range []string{"", "normal", "cache-only", "refresh"} {
+		if _, err := ParseFetchPolicy(s); err != nil {
+			t.Errorf("ParseFetchPolicy(%q) error: %v", s, err)
+		}
+	}
+	if _, err := ParseFetchPolicy("offline"); err == nil {
+		t.Errorf("ParseFetchPolicy(offline) expected error")
+	}
+}
@@ -1,5 +1,7 @@
 package campwiz
 
+import "math"
+
 type Property struct {
 	ID          string        `yaml:"id"` // Must be unique, suggested form: /<state>/<area>/<name>
 	URL         string        `yaml:"url,omitempty"`
@@ -16,6 +18,9 @@ type Campground struct {
 	ResURL string `yaml:"res_url,omitempty"`
 	ResID  string `yaml:"res_id,omitempty"`
 
+	// ProviderIDs are the result IDs each backend provider uses for this campground
+	ProviderIDs map[string][]string `yaml:"provider_ids,omitempty"`
+
 	Refs map[string]*Ref
 
 	PropertyID string `yaml:"__property_id__,omitempty"` // internal reference back
@@ -51,6 +56,37 @@ type Source struct {
 	URL        string  `yaml:"url,omitempty"`
 	RatingMax  float64 `yaml:"rating_max,omitempty"`
 	RatingDesc string  `yaml:"rating_desc,omitempty"`
+
+	// Weight is how much this sources ratings count towards an aggregate rating, defaulting to 1 if unset
+	Weight *float64 `yaml:"weight,omitempty"`
+	// Trust is how reliable this source is, from 0 to 1, defaulting to 1 if unset
+	Trust *float64 `yaml:"trust,omitempty"`
+}
+
+// RatingScale is the common scale that ratings from every source are normalized to
+const RatingScale = 10.0
+
+// Normalize converts a rating from this source to RatingScale. Ratings from sources
+// without a RatingMax are assumed to already be on that scale.
+func (s Source) Normalize(rating float64) float64 {
+	if s.RatingMax <= 0 {
+		return rating
+	}
+	return rating / s.RatingMax * RatingScale
+}
+
+// EffectiveWeight returns the weight of this sources ratings, discounted by trust. A source
+// with a weight or trust of 0 does not count at all.
+func (s Source) EffectiveWeight() float64 {
+	w := 1.0
+	if s.Weight != nil {
+		w = math.Max(0, *s.Weight)
+	}
+	t := 1.0
+	if s.Trust != nil {
+		t = math.Max(0, math.Min(1, *s.Trust))
+	}
+	return w * t
 }
 
 type RefFile struct {
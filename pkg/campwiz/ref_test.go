@@ -0,0 +1,29 @@
+package campwiz
+
+import "testing"
+
+func TestEffectiveWeight(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name string
+		in   Source
+		want float64
+	}{
+		{name: "unset", in: Source{}, want: 1},
+		{name: "weight", in: Source{Weight: f(3)}, want: 3},
+		{name: "weight and trust", in: Source{Weight: f(3), Trust: f(0.5)}, want: 1.5},
+		{name: "no trust", in: Source{Trust: f(0)}, want: 0},
+		{name: "no weight", in: Source{Weight: f(0), Trust: f(1)}, want: 0},
+		{name: "trust above 1", in: Source{Trust: f(4)}, want: 1},
+		{name: "negative trust", in: Source{Trust: f(-1)}, want: 0},
+		{name: "negative weight", in: Source{Weight: f(-2)}, want: 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.in.EffectiveWeight(); got != tc.want {
+				t.Errorf("EffectiveWeight() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package campwiz
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -15,17 +16,43 @@ type Availability struct {
 
 	Date time.Time
 	URL  string
+
+	// Provider is the backend which URL books this availability through
+	Provider string
 }
 
 // Result is supposed to be a vendor neutral result of results
 type Result struct {
+	// Provider is the backend which returned this result
+	Provider string
+	// Providers are every backend which returned this result, once merged across providers
+	Providers []string
+
 	ResURL string
 	ResID  string
 
-	Name     string
+	Name string
+	// Distance is in miles from the query location, combined from every query origin, or from the query route
 	Distance float64
+	// RouteMiles is how far along the query route the result is reached
+	RouteMiles float64
+	// DriveTime is the estimated drive from the query location, or combined from every query origin.
+	// It is zero if unknown, and negative if there is no known route.
+	DriveTime time.Duration
+	// DriveTimes are from each of the query origins, in order, if known
+	DriveTimes []time.Duration
+	// Distances are from each of the query origins, in order, if the result coordinates are known
+	Distances []float64
+	// Lat and Lon are the coordinates of the result, if known
+	Lat float64
+	Lon float64
 
+	// Rating is the weighted aggregate of every source rating, on a scale of 0 to RatingScale
 	Rating float64
+	// RatingConfidence is how much the aggregate rating can be relied on, from 0 to 1
+	RatingConfidence float64
+	// RatingSources is the number of sources which rated this result
+	RatingSources int
 
 	Desc string
 	URL  string
@@ -37,4 +64,21 @@ type Result struct {
 	Locale       string
 
 	KnownCampground *Campground
+	// MatchScore and MatchDetail describe why the result was linked to KnownCampground
+	MatchScore  string
+	MatchDetail string
+
+	// Refreshing is set if availability came from a stale page which is being refreshed
+	Refreshing bool
+}
+
+// RatingSummary describes the aggregate rating and how many sources it is based on, such as "8.2 (3 sources)"
+func (r Result) RatingSummary() string {
+	switch r.RatingSources {
+	case 0:
+		return "unrated"
+	case 1:
+		return fmt.Sprintf("%.1f (1 source)", r.Rating)
+	}
+	return fmt.Sprintf("%.1f (%d sources)", r.Rating, r.RatingSources)
 }
@@ -1,5 +1,12 @@
 package campwiz
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SiteKind is the kind of site that is available, such as a tent site
 type SiteKind string
 
 const (
@@ -31,3 +38,45 @@ const (
 	Beach                  = 4012
 	Winter                 = 4013
 )
+
+// kindNames are the names of each kind of site, as people search for them
+var kindNames = map[string][]SiteKind{
+	"tent":       {Tent},
+	"rv":         {RV, AccessibleRV},
+	"car":        {Standard, AccessibleStandard},
+	"standard":   {Standard, AccessibleStandard},
+	"accessible": {AccessibleRV, AccessibleStandard},
+	"cabin":      {Lodging},
+	"lodging":    {Lodging},
+	"yurt":       {Lodging},
+	"group":      {Group},
+	"day":        {Day},
+	"picnic":     {Day},
+	"horse":      {Equestrian},
+	"equestrian": {Equestrian},
+	"boat":       {Boat},
+	"walk-in":    {Walk},
+	"hike-in":    {Walk},
+}
+
+// ParseSiteKinds returns the kinds of site that a name such as "tent" or "RVs" refers to
+func ParseSiteKinds(name string) ([]SiteKind, error) {
+	n := strings.ToLower(name)
+	if ks, ok := kindNames[n]; ok {
+		return ks, nil
+	}
+	if ks, ok := kindNames[strings.TrimSuffix(n, "s")]; ok {
+		return ks, nil
+	}
+	return nil, fmt.Errorf("unknown kind of site %q: want one of %s", name, strings.Join(SiteKindNames(), ", "))
+}
+
+// SiteKindNames returns the names accepted by ParseSiteKinds, in order
+func SiteKindNames() []string {
+	ns := []string{}
+	for n := range kindNames {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
@@ -0,0 +1,34 @@
+package campwiz
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseSiteKinds(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []SiteKind
+		wantErr bool
+	}{
+		{in: "tent", want: []SiteKind{Tent}},
+		{in: "Tents", want: []SiteKind{Tent}},
+		{in: "RVs", want: []SiteKind{RV, AccessibleRV}},
+		{in: "cabins", want: []SiteKind{Lodging}},
+		{in: "walk-in", want: []SiteKind{Walk}},
+		{in: "treehouse", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseSiteKinds(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseSiteKinds(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseSiteKinds(%q) unexpected diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
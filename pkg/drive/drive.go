@@ -0,0 +1,102 @@
+// Package drive estimates how long it takes to drive between places
+package drive
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"k8s.io/klog/v2"
+)
+
+// Router converts places to drive times
+type Router interface {
+	// Name is a human readable name for the router
+	Name() string
+	// Durations returns the drive time from one place to each of many. A negative duration
+	// means that there is no known route.
+	Durations(from geo.Point, to []geo.Point) ([]time.Duration, error)
+}
+
+// Config is runtime configuration
+type Config struct {
+	// Type of router to use: none, heuristic, or osrm
+	Type string
+	// URL is the base URL of an OSRM-compatible server, such as https://router.project-osrm.org
+	URL string
+	// Store is the cache implementation to use
+	Store cache.Store
+	// Policy controls whether routes may be fetched from the cache, the network, or both
+	Policy cache.Policy
+}
+
+// New returns an appropriately configured router, or nil if drive times are not wanted
+func New(c Config) (Router, error) {
+	switch c.Type {
+	case "", "none":
+		return nil, nil
+	case "heuristic":
+		return Heuristic{}, nil
+	case "osrm":
+		if c.URL == "" {
+			return nil, fmt.Errorf("osrm router requires a URL")
+		}
+		return &fallback{primary: &OSRM{url: c.URL, store: c.Store, policy: c.Policy}, secondary: Heuristic{}}, nil
+	default:
+		return nil, fmt.Errorf("unknown router type: %q", c.Type)
+	}
+}
+
+const (
+	// circuity is how much further roads travel than a straight line, on average
+	circuity = 1.3
+	// averageMPH is the average speed of a drive, including slower roads near either end
+	averageMPH = 45.0
+)
+
+// Heuristic estimates drive times from straight-line distances
+type Heuristic struct{}
+
+// Name is a human readable name for the router
+func (Heuristic) Name() string { return "heuristic" }
+
+// Durations returns the estimated drive time from one place to each of many
+func (Heuristic) Durations(from geo.Point, to []geo.Point) ([]time.Duration, error) {
+	ds := []time.Duration{}
+	for _, p := range to {
+		miles := geo.MilesApart(from.Lat, from.Lon, p.Lat, p.Lon) * circuity
+		ds = append(ds, time.Duration(miles/averageMPH*float64(time.Hour)).Round(time.Minute))
+	}
+	return ds, nil
+}
+
+// fallback is a router which estimates drive times if its primary router fails
+type fallback struct {
+	primary   Router
+	secondary Router
+}
+
+func (f *fallback) Name() string { return f.primary.Name() }
+
+func (f *fallback) Durations(from geo.Point, to []geo.Point) ([]time.Duration, error) {
+	ds, err := f.primary.Durations(from, to)
+	if err == nil {
+		return ds, nil
+	}
+	klog.Warningf("%s router failed, falling back to %s: %v", f.primary.Name(), f.secondary.Name(), err)
+	return f.secondary.Durations(from, to)
+}
+
+// Summary describes a drive time, such as "2h10m drive", or "" if it is unknown
+func Summary(d time.Duration) string {
+	switch {
+	case d < 0:
+		return "no known route"
+	case d == 0:
+		return ""
+	case d < time.Hour:
+		return fmt.Sprintf("%dm drive", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm drive", int(d.Hours()), int(d.Minutes())%60)
+}
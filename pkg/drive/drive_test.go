@@ -0,0 +1,74 @@
+package drive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+var (
+	sanFrancisco = geo.Point{Lat: 37.7749, Lon: -122.4194}
+	sacramento   = geo.Point{Lat: 38.5816, Lon: -121.4944}
+	tahoe        = geo.Point{Lat: 38.9399, Lon: -119.9772}
+)
+
+func TestHeuristic(t *testing.T) {
+	got, err := Heuristic{}.Durations(sanFrancisco, []geo.Point{sanFrancisco, sacramento, tahoe})
+	if err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	want := []time.Duration{0, 2*time.Hour + 10*time.Minute, 4*time.Hour + 28*time.Minute}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Durations() unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		c        Config
+		wantName string
+		wantErr  bool
+	}{
+		{c: Config{}, wantName: ""},
+		{c: Config{Type: "none"}, wantName: ""},
+		{c: Config{Type: "heuristic"}, wantName: "heuristic"},
+		{c: Config{Type: "osrm", URL: "http://localhost:5000"}, wantName: "osrm"},
+		{c: Config{Type: "osrm"}, wantErr: true},
+		{c: Config{Type: "teleport"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.c.Type, func(t *testing.T) {
+			r, err := New(tc.c)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("New(%+v) error = %v, wantErr %v", tc.c, err, tc.wantErr)
+			}
+			name := ""
+			if r != nil {
+				name = r.Name()
+			}
+			if name != tc.wantName {
+				t.Errorf("New(%+v) = %q router, want %q", tc.c, name, tc.wantName)
+			}
+		})
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: 0, want: ""},
+		{in: -1, want: "no known route"},
+		{in: 45 * time.Minute, want: "45m drive"},
+		{in: 2*time.Hour + 5*time.Minute, want: "2h05m drive"},
+	}
+	for _, tc := range tests {
+		if got := Summary(tc.in); got != tc.want {
+			t.Errorf("Summary(%s) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
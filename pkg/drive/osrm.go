@@ -0,0 +1,117 @@
+package drive
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// osrmBatch is how many places to request drive times to at once, as public servers limit table sizes
+	osrmBatch = 90
+	// osrmExpiry is how long drive times can be cached for
+	osrmExpiry = 30 * 24 * time.Hour
+)
+
+// OSRM requests drive times from the table service of an OSRM-compatible server
+type OSRM struct {
+	url    string
+	store  cache.Store
+	policy cache.Policy
+}
+
+// osrmTable is a response from the OSRM table service
+type osrmTable struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Durations are in seconds, from each source to each destination. Unroutable pairs are null.
+	Durations [][]*float64 `json:"durations"`
+}
+
+// Name is a human readable name for the router
+func (o *OSRM) Name() string { return "osrm" }
+
+// Durations returns the drive time from one place to each of many
+func (o *OSRM) Durations(from geo.Point, to []geo.Point) ([]time.Duration, error) {
+	ds := []time.Duration{}
+	for start := 0; start < len(to); start += osrmBatch {
+		end := start + osrmBatch
+		if end > len(to) {
+			end = len(to)
+		}
+		bds, err := o.table(from, to[start:end])
+		if err != nil {
+			return nil, err
+		}
+		ds = append(ds, bds...)
+	}
+	return ds, nil
+}
+
+// table requests the drive times from one place to a batch of others
+func (o *OSRM) table(from geo.Point, to []geo.Point) ([]time.Duration, error) {
+	coords := []string{coordinate(from)}
+	for _, p := range to {
+		coords = append(coords, coordinate(p))
+	}
+
+	req := cache.Request{
+		Provider: "osrm",
+		Method:   "GET",
+		URL:      fmt.Sprintf("%s/table/v1/driving/%s?sources=0&annotations=duration", strings.TrimRight(o.url, "/"), strings.Join(coords, ";")),
+		MaxAge:   osrmExpiry,
+		Policy:   o.policy,
+	}
+
+	resp, err := cache.Fetch(req, o.store)
+	if err != nil {
+		return nil, fmt.Errorf("fetch: %w", err)
+	}
+
+	ds, err := parseTable(resp.Body, len(to))
+	if err != nil {
+		// Failures should be retried rather than served from the cache until they expire
+		if l, ok := o.store.(cache.Lister); ok {
+			if derr := l.Delete(req.Key()); derr != nil {
+				klog.Errorf("unable to forget failed %s: %v", req.Key(), derr)
+			}
+		}
+		return nil, fmt.Errorf("status %d: %w", resp.StatusCode, err)
+	}
+	return ds, nil
+}
+
+// parseTable parses the drive times from one place to n others within an OSRM table response
+func parseTable(bs []byte, n int) ([]time.Duration, error) {
+	var t osrmTable
+	if err := json.Unmarshal(bs, &t); err != nil {
+		return nil, fmt.Errorf("parse: %w", err)
+	}
+	if t.Code != "Ok" {
+		return nil, fmt.Errorf("table: %s: %s", t.Code, t.Message)
+	}
+	if len(t.Durations) != 1 || len(t.Durations[0]) != n+1 {
+		return nil, fmt.Errorf("table: got %d rows, want 1 row of %d durations", len(t.Durations), n+1)
+	}
+
+	ds := []time.Duration{}
+	// The first destination is the source itself
+	for _, s := range t.Durations[0][1:] {
+		if s == nil {
+			ds = append(ds, -1)
+			continue
+		}
+		ds = append(ds, time.Duration(*s*float64(time.Second)).Round(time.Minute))
+	}
+	return ds, nil
+}
+
+// coordinate formats a place as OSRM expects: longitude first
+func coordinate(p geo.Point) string {
+	return fmt.Sprintf("%.5f,%.5f", p.Lon, p.Lat)
+}
@@ -0,0 +1,133 @@
+package drive
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+// fakeOSRM is a stand-in for the table service of an OSRM server. Each destination is ten
+// minutes further than the last, and destinations at the north pole can not be reached.
+type fakeOSRM struct {
+	requests []string
+	fail     bool
+}
+
+func (f *fakeOSRM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests = append(f.requests, r.URL.Path)
+	if f.fail {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":"InvalidQuery","message":"Query string malformed"}`)
+		return
+	}
+
+	coords := strings.Split(strings.TrimPrefix(r.URL.Path, "/table/v1/driving/"), ";")
+	ds := []string{}
+	for i, c := range coords {
+		if strings.HasSuffix(c, ",90.00000") {
+			ds = append(ds, "null")
+			continue
+		}
+		ds = append(ds, fmt.Sprintf("%d.4", i*600))
+	}
+	fmt.Fprintf(w, `{"code":"Ok","durations":[[%s]]}`, strings.Join(ds, ","))
+}
+
+func TestOSRM(t *testing.T) {
+	f := &fakeOSRM{}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	r, err := New(Config{Type: "osrm", URL: ts.URL + "/", Store: cache.NewMemory(cache.MemoryConfig{})})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	got, err := r.Durations(sanFrancisco, []geo.Point{sacramento, {Lat: 90, Lon: 0}, tahoe})
+	if err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	want := []time.Duration{10 * time.Minute, -1, 30 * time.Minute}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Durations() unexpected diff (-want +got):\n%s", diff)
+	}
+
+	wantPath := "/table/v1/driving/-122.41940,37.77490;-121.49440,38.58160;0.00000,90.00000;-119.97720,38.93990"
+	if len(f.requests) != 1 || f.requests[0] != wantPath {
+		t.Errorf("requests = %v, want [%s]", f.requests, wantPath)
+	}
+
+	// Drive times are cached
+	if _, err := r.Durations(sanFrancisco, []geo.Point{sacramento, {Lat: 90, Lon: 0}, tahoe}); err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	if len(f.requests) != 1 {
+		t.Errorf("got %d requests, want the second lookup to be cached", len(f.requests))
+	}
+}
+
+func TestOSRMBatches(t *testing.T) {
+	f := &fakeOSRM{}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	to := []geo.Point{}
+	for i := 0; i < osrmBatch+10; i++ {
+		to = append(to, geo.Point{Lat: 38, Lon: -122 + float64(i)/100})
+	}
+
+	o := &OSRM{url: ts.URL, store: cache.NewMemory(cache.MemoryConfig{})}
+	got, err := o.Durations(sanFrancisco, to)
+	if err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	if len(got) != len(to) || len(f.requests) != 2 {
+		t.Fatalf("got %d durations in %d requests, want %d in 2", len(got), len(f.requests), len(to))
+	}
+	// The second batch starts counting again
+	if got[osrmBatch-1] != time.Duration(osrmBatch)*10*time.Minute || got[osrmBatch] != 10*time.Minute {
+		t.Errorf("durations either side of the batch = %s, %s", got[osrmBatch-1], got[osrmBatch])
+	}
+}
+
+func TestOSRMFallback(t *testing.T) {
+	f := &fakeOSRM{fail: true}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+
+	cs := cache.NewMemory(cache.MemoryConfig{})
+	o := &OSRM{url: ts.URL, store: cs}
+	if _, err := o.Durations(sanFrancisco, []geo.Point{sacramento}); err == nil {
+		t.Errorf("Durations() = nil error, want one for a failed request")
+	}
+
+	r, err := New(Config{Type: "osrm", URL: ts.URL, Store: cs})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	got, err := r.Durations(sanFrancisco, []geo.Point{sacramento})
+	if err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	want, _ := Heuristic{}.Durations(sanFrancisco, []geo.Point{sacramento})
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Durations() did not fall back to the heuristic (-want +got):\n%s", diff)
+	}
+
+	// Failures are not cached
+	f.fail = false
+	got, err = r.Durations(sanFrancisco, []geo.Point{sacramento})
+	if err != nil {
+		t.Fatalf("Durations: %v", err)
+	}
+	if diff := cmp.Diff([]time.Duration{10 * time.Minute}, got); diff != "" {
+		t.Errorf("Durations() after recovery unexpected diff (-want +got):\n%s", diff)
+	}
+}
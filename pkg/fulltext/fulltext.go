@@ -0,0 +1,201 @@
+// Package fulltext matches free-form queries against text, with stemming, phrases and negation
+package fulltext
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed full-text query. Every phrase must be found, and no negated phrase may be.
+type Query struct {
+	Phrases [][]string
+	Negated [][]string
+}
+
+// Parse parses a query such as `lake "redwood grove" -rv`. Words are matched by their stem,
+// quoted words must appear together, and a leading "-" excludes a word or quoted phrase.
+func Parse(s string) (Query, error) {
+	q := Query{}
+	rs := []rune(s)
+
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		negated := false
+		if rs[i] == '-' {
+			negated = true
+			i++
+		}
+
+		var text string
+		if i < len(rs) && rs[i] == '"' {
+			end := i + 1
+			for end < len(rs) && rs[end] != '"' {
+				end++
+			}
+			if end == len(rs) {
+				return q, fmt.Errorf("unterminated quote in %q", s)
+			}
+			text = string(rs[i+1 : end])
+			i = end + 1
+		} else {
+			start := i
+			for i < len(rs) && !unicode.IsSpace(rs[i]) {
+				i++
+			}
+			text = string(rs[start:i])
+		}
+
+		ts := Tokenize(text)
+		if len(ts) == 0 {
+			if negated {
+				return q, fmt.Errorf("nothing to exclude after \"-\" in %q", s)
+			}
+			continue
+		}
+
+		if negated {
+			q.Negated = append(q.Negated, ts)
+		} else {
+			q.Phrases = append(q.Phrases, ts)
+		}
+	}
+	return q, nil
+}
+
+// Empty returns whether the query matches everything
+func (q Query) Empty() bool {
+	return len(q.Phrases) == 0 && len(q.Negated) == 0
+}
+
+// Match returns whether a set of documents satisfy the query, as if they were one
+func (q Query) Match(ds ...*Document) bool {
+	for _, p := range q.Negated {
+		for _, d := range ds {
+			if d.Contains(p) {
+				return false
+			}
+		}
+	}
+
+	for _, p := range q.Phrases {
+		found := false
+		for _, d := range ds {
+			if d.Contains(p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Document is tokenized text which queries are matched against
+type Document struct {
+	tokens []string
+	set    map[string]bool
+}
+
+// NewDocument returns a document of fields. Phrases are never matched across fields.
+func NewDocument(fields ...string) *Document {
+	d := &Document{set: map[string]bool{}}
+	for _, f := range fields {
+		ts := Tokenize(f)
+		if len(ts) == 0 {
+			continue
+		}
+		for _, t := range ts {
+			d.set[t] = true
+		}
+		// An empty token separates fields, and can not be within any phrase
+		d.tokens = append(d.tokens, ts...)
+		d.tokens = append(d.tokens, "")
+	}
+	return d
+}
+
+// Contains returns whether a document contains a phrase of tokens
+func (d *Document) Contains(phrase []string) bool {
+	if d == nil || len(phrase) == 0 {
+		return false
+	}
+	for _, t := range phrase {
+		if !d.set[t] {
+			return false
+		}
+	}
+	if len(phrase) == 1 {
+		return true
+	}
+
+	for i := 0; i+len(phrase) <= len(d.tokens); i++ {
+		found := true
+		for j, t := range phrase {
+			if d.tokens[i+j] != t {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+// Tokenize splits text into lowercase stemmed words
+func Tokenize(s string) []string {
+	ws := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range ws {
+		ws[i] = Stem(w)
+	}
+	return ws
+}
+
+// Stem reduces an English word to a stem shared by its plural and verb forms, so that
+// "hikes", "hiking" and "hiked" are all matched by "hike". Stems are not always words.
+func Stem(w string) string {
+	if len([]rune(w)) <= 3 {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(w, "ies") && len(w) > 4:
+		w = w[:len(w)-3] + "y"
+	case strings.HasSuffix(w, "sses"), strings.HasSuffix(w, "shes"), strings.HasSuffix(w, "ches"), strings.HasSuffix(w, "xes"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ss"), strings.HasSuffix(w, "us"), strings.HasSuffix(w, "is"):
+	case strings.HasSuffix(w, "s"):
+		w = w[:len(w)-1]
+	}
+
+	switch {
+	case strings.HasSuffix(w, "ing") && len(w) > 5:
+		w = undouble(w[:len(w)-3])
+	case strings.HasSuffix(w, "ed") && len(w) > 4:
+		w = undouble(w[:len(w)-2])
+	}
+
+	if len(w) > 3 {
+		w = strings.TrimSuffix(w, "e")
+	}
+	return w
+}
+
+// undouble removes a doubled final consonant, as in "swimm" from "swimming"
+func undouble(w string) string {
+	n := len(w)
+	if n < 2 || w[n-1] != w[n-2] || strings.ContainsRune("aeiouls", rune(w[n-1])) {
+		return w
+	}
+	return w[:n-1]
+}
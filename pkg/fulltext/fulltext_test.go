@@ -0,0 +1,97 @@
+package fulltext
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStem(t *testing.T) {
+	groups := [][]string{
+		{"hike", "hikes", "hiking", "hiked"},
+		{"redwood", "redwoods"},
+		{"beach", "beaches"},
+		{"swim", "swimming"},
+		{"lake", "lakes"},
+		{"family", "families"},
+		{"campground", "campgrounds"},
+	}
+	for _, g := range groups {
+		want := Stem(g[0])
+		for _, w := range g[1:] {
+			if got := Stem(w); got != want {
+				t.Errorf("Stem(%q) = %q, want %q (as for %q)", w, got, want, g[0])
+			}
+		}
+	}
+
+	for _, w := range []string{"rv", "grass", "cactus", "oasis"} {
+		if got := Stem(w); got != w {
+			t.Errorf("Stem(%q) = %q, want it unchanged", w, got)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Query
+		wantErr bool
+	}{
+		{in: "", want: Query{}},
+		{in: "lake -rv", want: Query{Phrases: [][]string{{"lak"}}, Negated: [][]string{{"rv"}}}},
+		{in: `"Redwood Groves" beaches`, want: Query{Phrases: [][]string{{"redwood", "grov"}, {"beach"}}}},
+		{in: `-"rv park" ocean-view`, want: Query{Phrases: [][]string{{"ocean", "view"}}, Negated: [][]string{{"rv", "park"}}}},
+		{in: "lake !!", want: Query{Phrases: [][]string{{"lak"}}}},
+		{in: `"lake`, wantErr: true},
+		{in: "lake -", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := Parse(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Parse(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Parse(%q) unexpected diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	name := NewDocument("Big Basin Redwoods State Park")
+	desc := NewDocument("Hike among ancient redwood trees, a short drive from the beach.", "RV hookups available")
+
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{query: "", want: true},
+		{query: "redwood", want: true},
+		{query: "hiking beaches", want: true},
+		{query: `"redwood trees"`, want: true},
+		{query: `"trees redwood"`, want: false},
+		{query: `"state park hike"`, want: false},
+		{query: `"beach rv"`, want: false},
+		{query: "redwoods -rv", want: false},
+		{query: `redwoods -"rv park"`, want: true},
+		{query: "lake", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := Parse(tc.query)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.query, err)
+			}
+			if got := q.Match(name, desc); got != tc.want {
+				t.Errorf("Match(%q) = %v, want %v", tc.query, got, tc.want)
+			}
+		})
+	}
+}
@@ -19,3 +19,32 @@ func MilesApart(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64
 	dist = dist * 180 / math.Pi
 	return dist * 60 * 1.1515
 }
+
+// Point is a location on the earth, in degrees
+type Point struct {
+	Lat float64
+	Lon float64
+}
+
+// Center returns the geographic midpoint of a set of points
+func Center(ps ...Point) Point {
+	if len(ps) == 0 {
+		return Point{}
+	}
+
+	var x, y, z float64
+	for _, p := range ps {
+		lat := p.Lat * math.Pi / 180
+		lon := p.Lon * math.Pi / 180
+		x += math.Cos(lat) * math.Cos(lon)
+		y += math.Cos(lat) * math.Sin(lon)
+		z += math.Sin(lat)
+	}
+
+	n := float64(len(ps))
+	x, y, z = x/n, y/n, z/n
+	return Point{
+		Lat: math.Atan2(z, math.Sqrt(x*x+y*y)) * 180 / math.Pi,
+		Lon: math.Atan2(y, x) * 180 / math.Pi,
+	}
+}
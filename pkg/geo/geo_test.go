@@ -0,0 +1,62 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMilesApart(t *testing.T) {
+	// San Francisco to Sacramento
+	got := MilesApart(37.7749, -122.4194, 38.5816, -121.4944)
+	if math.Abs(got-75.3) > 1 {
+		t.Errorf("MilesApart() = %.1f, want about 75.3", got)
+	}
+}
+
+func TestCenter(t *testing.T) {
+	sf := Point{Lat: 37.7749, Lon: -122.4194}
+	sac := Point{Lat: 38.5816, Lon: -121.4944}
+
+	tests := []struct {
+		name string
+		in   []Point
+		want Point
+	}{
+		{name: "none", in: nil, want: Point{}},
+		{name: "one", in: []Point{sf}, want: sf},
+		{name: "two", in: []Point{sf, sac}, want: Point{Lat: 38.1799, Lon: -121.9592}},
+		{name: "antimeridian", in: []Point{{Lat: 0, Lon: 179}, {Lat: 0, Lon: -179}}, want: Point{Lat: 0, Lon: 180}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Center(tc.in...)
+			if math.Abs(got.Lat-tc.want.Lat) > 0.001 || math.Abs(math.Mod(got.Lon-tc.want.Lon+540, 360)-180) > 0.001 {
+				t.Errorf("Center() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	// A midpoint is equally far from both ends
+	c := Center(sf, sac)
+	a, b := MilesApart(c.Lat, c.Lon, sf.Lat, sf.Lon), MilesApart(c.Lat, c.Lon, sac.Lat, sac.Lon)
+	if math.Abs(a-b) > 0.1 {
+		t.Errorf("Center() is %.1fmi from one end and %.1fmi from the other", a, b)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, name := range []string{"Big Sur", "big  sur", " BIG SUR"} {
+		if got, ok := Lookup(name); !ok || math.Abs(got.Lat-36.27) > 0.01 || math.Abs(got.Lon+121.81) > 0.01 {
+			t.Errorf("Lookup(%q) = %+v, %v, want about 36.27,-121.81", name, got, ok)
+		}
+	}
+	if got, ok := Lookup("Atlantis"); ok {
+		t.Errorf("Lookup(%q) = %+v, want not found", "Atlantis", got)
+	}
+	for _, n := range PlaceNames() {
+		if _, ok := Lookup(n); !ok {
+			t.Errorf("Lookup(%q) not found, but it is listed by PlaceNames()", n)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package geo
+
+import (
+	"sort"
+	"strings"
+)
+
+// places are well-known destinations which may be searched near by name
+var places = map[string]Point{
+	"big sur":          {Lat: 36.2704, Lon: -121.8081},
+	"bishop":           {Lat: 37.3635, Lon: -118.3951},
+	"crescent city":    {Lat: 41.7558, Lon: -124.2026},
+	"death valley":     {Lat: 36.4614, Lon: -116.8656},
+	"fresno":           {Lat: 36.7378, Lon: -119.7871},
+	"joshua tree":      {Lat: 34.1347, Lon: -116.3131},
+	"lake tahoe":       {Lat: 38.9399, Lon: -119.9772},
+	"lassen":           {Lat: 40.4977, Lon: -121.4207},
+	"los angeles":      {Lat: 34.0522, Lon: -118.2437},
+	"mammoth lakes":    {Lat: 37.6485, Lon: -118.9721},
+	"mendocino":        {Lat: 39.3077, Lon: -123.7995},
+	"monterey":         {Lat: 36.6002, Lon: -121.8947},
+	"mount shasta":     {Lat: 41.3099, Lon: -122.3106},
+	"mountain view":    {Lat: 37.3861, Lon: -122.0839},
+	"oakland":          {Lat: 37.8044, Lon: -122.2712},
+	"palm springs":     {Lat: 33.8303, Lon: -116.5453},
+	"pinnacles":        {Lat: 36.4906, Lon: -121.1825},
+	"point reyes":      {Lat: 38.0690, Lon: -122.8069},
+	"sacramento":       {Lat: 38.5816, Lon: -121.4944},
+	"san diego":        {Lat: 32.7157, Lon: -117.1611},
+	"san francisco":    {Lat: 37.7749, Lon: -122.4194},
+	"san jose":         {Lat: 37.3382, Lon: -121.8863},
+	"santa barbara":    {Lat: 34.4208, Lon: -119.6982},
+	"santa cruz":       {Lat: 36.9741, Lon: -122.0308},
+	"sequoia":          {Lat: 36.4864, Lon: -118.5658},
+	"sonoma":           {Lat: 38.2919, Lon: -122.4580},
+	"south lake tahoe": {Lat: 38.9399, Lon: -119.9772},
+	"yosemite":         {Lat: 37.7456, Lon: -119.5936},
+}
+
+// Lookup returns the coordinates of a well-known place, such as "Big Sur"
+func Lookup(name string) (Point, bool) {
+	p, ok := places[strings.ToLower(strings.Join(strings.Fields(name), " "))]
+	return p, ok
+}
+
+// PlaceNames returns the names of every well-known place, in order
+func PlaceNames() []string {
+	ns := []string{}
+	for n := range places {
+		ns = append(ns, n)
+	}
+	sort.Strings(ns)
+	return ns
+}
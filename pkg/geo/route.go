@@ -0,0 +1,164 @@
+package geo
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Length returns the length of a path in miles
+func Length(path []Point) float64 {
+	total := 0.0
+	for i := 1; i < len(path); i++ {
+		total += MilesApart(path[i-1].Lat, path[i-1].Lon, path[i].Lat, path[i].Lon)
+	}
+	return total
+}
+
+// Sample returns points along a path, no more than every miles apart, including both ends
+func Sample(path []Point, every float64) []Point {
+	if len(path) < 2 || every <= 0 {
+		return path
+	}
+
+	ps := []Point{path[0]}
+	// next is how far along the current segment the next sample is
+	next := every
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		seg := MilesApart(a.Lat, a.Lon, b.Lat, b.Lon)
+		for ; next < seg; next += every {
+			ps = append(ps, interpolate(a, b, next/seg))
+		}
+		next -= seg
+	}
+
+	last := path[len(path)-1]
+	if ps[len(ps)-1] != last {
+		ps = append(ps, last)
+	}
+	return ps
+}
+
+// Project returns how far along a path the closest point to p is, and how far p is from it, in miles
+func Project(path []Point, p Point) (along float64, off float64) {
+	if len(path) == 0 {
+		return 0, -1
+	}
+	if len(path) == 1 {
+		return 0, MilesApart(p.Lat, p.Lon, path[0].Lat, path[0].Lon)
+	}
+
+	off = math.MaxFloat64
+	travelled := 0.0
+	for i := 1; i < len(path); i++ {
+		a, b := path[i-1], path[i]
+		seg := MilesApart(a.Lat, a.Lon, b.Lat, b.Lon)
+		t := closest(a, b, p)
+		c := interpolate(a, b, t)
+		if d := MilesApart(p.Lat, p.Lon, c.Lat, c.Lon); d < off {
+			off = d
+			along = travelled + t*seg
+		}
+		travelled += seg
+	}
+	return along, off
+}
+
+// closest returns the fraction of the way from a to b which is closest to p, using a flat
+// approximation of the earth which holds for the length of a road segment.
+func closest(a, b, p Point) float64 {
+	scale := math.Cos((a.Lat + b.Lat) / 2 * math.Pi / 180)
+	dx, dy := (b.Lon-a.Lon)*scale, b.Lat-a.Lat
+	if dx == 0 && dy == 0 {
+		return 0
+	}
+	px, py := (p.Lon-a.Lon)*scale, p.Lat-a.Lat
+	t := (px*dx + py*dy) / (dx*dx + dy*dy)
+	return math.Max(0, math.Min(1, t))
+}
+
+// interpolate returns the point a fraction t of the way from a to b
+func interpolate(a, b Point, t float64) Point {
+	return Point{Lat: a.Lat + (b.Lat-a.Lat)*t, Lon: a.Lon + (b.Lon-a.Lon)*t}
+}
+
+// ParsePath parses waypoints such as "37.77,-122.42; 37.64,-118.97; 37.36,-118.39"
+func ParsePath(s string) ([]Point, error) {
+	path := []Point{}
+	for _, w := range strings.Split(s, ";") {
+		if strings.TrimSpace(w) == "" {
+			continue
+		}
+		parts := strings.Split(w, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("waypoint %q: want latitude,longitude", strings.TrimSpace(w))
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return nil, fmt.Errorf("waypoint %q: invalid latitude", strings.TrimSpace(w))
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return nil, fmt.Errorf("waypoint %q: invalid longitude", strings.TrimSpace(w))
+		}
+		path = append(path, Point{Lat: lat, Lon: lon})
+	}
+
+	if len(path) < 2 {
+		return nil, fmt.Errorf("path %q: want at least two waypoints", s)
+	}
+	return path, nil
+}
+
+type gpxPoint struct {
+	Lat float64 `xml:"lat,attr"`
+	Lon float64 `xml:"lon,attr"`
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Segments []struct {
+			Points []gpxPoint `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+	Routes []struct {
+		Points []gpxPoint `xml:"rtept"`
+	} `xml:"rte"`
+	Waypoints []gpxPoint `xml:"wpt"`
+}
+
+// ParseGPX returns the path within a GPX file: its tracks, or else its routes, or else its waypoints
+func ParseGPX(r io.Reader) ([]Point, error) {
+	var f gpxFile
+	if err := xml.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+
+	var gps []gpxPoint
+	for _, t := range f.Tracks {
+		for _, s := range t.Segments {
+			gps = append(gps, s.Points...)
+		}
+	}
+	if len(gps) == 0 {
+		for _, rt := range f.Routes {
+			gps = append(gps, rt.Points...)
+		}
+	}
+	if len(gps) == 0 {
+		gps = f.Waypoints
+	}
+
+	path := []Point{}
+	for _, p := range gps {
+		path = append(path, Point{Lat: p.Lat, Lon: p.Lon})
+	}
+	if len(path) < 2 {
+		return nil, fmt.Errorf("found %d points, want at least two", len(path))
+	}
+	return path, nil
+}
@@ -0,0 +1,104 @@
+package geo
+
+import (
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// A path east along the equator, where a degree is about 69 miles
+var equator = []Point{{Lat: 0, Lon: 0}, {Lat: 0, Lon: 1}, {Lat: 0, Lon: 3}}
+
+func TestLength(t *testing.T) {
+	if got := Length(equator); math.Abs(got-207.3) > 0.1 {
+		t.Errorf("Length() = %.1f, want 207.3", got)
+	}
+	if got := Length(equator[:1]); got != 0 {
+		t.Errorf("Length() of one point = %.1f, want 0", got)
+	}
+}
+
+func TestSample(t *testing.T) {
+	got := Sample(equator, 50)
+	want := []Point{{0, 0}, {0, 0.7237}, {0, 1.4475}, {0, 2.1712}, {0, 2.8949}, {0, 3}}
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 0.001)); diff != "" {
+		t.Errorf("Sample() unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if got := Sample(equator, 1000); !cmp.Equal(got, []Point{equator[0], equator[2]}) {
+		t.Errorf("Sample() with a long interval = %v, want both ends", got)
+	}
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		p         Point
+		wantAlong float64
+		wantOff   float64
+	}{
+		{name: "on the path", p: Point{0, 2}, wantAlong: 138.2, wantOff: 0},
+		{name: "beside the path", p: Point{0.5, 1.5}, wantAlong: 103.6, wantOff: 34.5},
+		{name: "before the start", p: Point{0, -1}, wantAlong: 0, wantOff: 69.1},
+		{name: "beyond the end", p: Point{0, 4}, wantAlong: 207.3, wantOff: 69.1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			along, off := Project(equator, tc.p)
+			if math.Abs(along-tc.wantAlong) > 0.1 || math.Abs(off-tc.wantOff) > 0.1 {
+				t.Errorf("Project(%v) = %.1f, %.1f, want %.1f, %.1f", tc.p, along, off, tc.wantAlong, tc.wantOff)
+			}
+		})
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	got, err := ParsePath("37.77,-122.42; 37.64, -118.97;")
+	if err != nil {
+		t.Fatalf("ParsePath: %v", err)
+	}
+	if diff := cmp.Diff([]Point{{37.77, -122.42}, {37.64, -118.97}}, got); diff != "" {
+		t.Errorf("ParsePath() unexpected diff (-want +got):\n%s", diff)
+	}
+
+	for _, in := range []string{"", "37.77,-122.42", "37.77,-122.42;bishop", "37.77,-122.42;97.64,-118.97"} {
+		if _, err := ParsePath(in); err == nil {
+			t.Errorf("ParsePath(%q) = nil error, want one", in)
+		}
+	}
+}
+
+func TestParseGPX(t *testing.T) {
+	f, err := os.Open("testdata/bishop.gpx")
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+
+	got, err := ParseGPX(f)
+	if err != nil {
+		t.Fatalf("ParseGPX: %v", err)
+	}
+	want := []Point{{37.7749, -122.4194}, {37.9577, -121.2908}, {37.8651, -119.5383}, {37.9375, -119.2352}, {37.3635, -118.3951}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseGPX() unexpected diff (-want +got):\n%s", diff)
+	}
+
+	rte := `<gpx><rte><rtept lat="1" lon="2"/><rtept lat="3" lon="4"/></rte><wpt lat="5" lon="6"/></gpx>`
+	got, err = ParseGPX(strings.NewReader(rte))
+	if err != nil {
+		t.Fatalf("ParseGPX: %v", err)
+	}
+	if diff := cmp.Diff([]Point{{1, 2}, {3, 4}}, got); diff != "" {
+		t.Errorf("ParseGPX() of a route unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if _, err := ParseGPX(strings.NewReader(`<gpx><wpt lat="5" lon="6"/></gpx>`)); err == nil {
+		t.Errorf("ParseGPX() of one point = nil error, want one")
+	}
+}
@@ -0,0 +1,243 @@
+package metadata
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tstromberg/campwiz/pkg/relpath"
+	"k8s.io/klog/v2"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Binding ties the ID that a provider returns for a result to a known campground
+type Binding struct {
+	PropertyID   string
+	CampgroundID string
+	Provider     string
+	ID           string
+}
+
+func (b Binding) String() string {
+	return fmt.Sprintf("%s %q -> %s/%s", b.Provider, b.ID, b.PropertyID, b.CampgroundID)
+}
+
+// BindAll writes bindings into the metadata files which contain their campgrounds
+func BindAll(bs []Binding) error {
+	rest := bs
+	for _, f := range files {
+		path := relpath.Find(f)
+		var err error
+		rest, err = BindFile(path, rest)
+		if err != nil {
+			return fmt.Errorf("bind %s: %w", path, err)
+		}
+		if len(rest) == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown campgrounds: %v", rest)
+}
+
+// BindFile adds provider ID bindings to the campgrounds within a metadata file. Only the
+// provider_ids of each campground are rewritten, so that the formatting and order of the
+// rest of the file is preserved. Bindings for campgrounds outside of the file are returned.
+func BindFile(path string, bs []Binding) ([]Binding, error) {
+	in, err := ioutil.ReadFile(path)
+	if err != nil {
+		return bs, err
+	}
+
+	out, rest, err := bind(in, bs)
+	if err != nil {
+		return bs, err
+	}
+
+	if bytes.Equal(in, out) {
+		return rest, nil
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		return bs, err
+	}
+	klog.Infof("writing %d bindings to %s", len(bs)-len(rest), path)
+	return rest, ioutil.WriteFile(path, out, st.Mode())
+}
+
+// edit replaces a range of lines
+type edit struct {
+	// start and end are zero-based line offsets, end is exclusive
+	start int
+	end   int
+	lines []string
+}
+
+// bind adds provider ID bindings to a YAML metadata document
+func bind(in []byte, bs []Binding) ([]byte, []Binding, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(in, &doc); err != nil {
+		return nil, bs, fmt.Errorf("unmarshal: %w", err)
+	}
+
+	cgs := campgroundNodes(&doc)
+	wanted := map[*yaml.Node][]Binding{}
+	order := []*yaml.Node{}
+	rest := []Binding{}
+	for _, b := range bs {
+		n := cgs[b.PropertyID+"/"+b.CampgroundID]
+		if n == nil {
+			rest = append(rest, b)
+			continue
+		}
+		if wanted[n] == nil {
+			order = append(order, n)
+		}
+		wanted[n] = append(wanted[n], b)
+	}
+
+	edits := []edit{}
+	for _, n := range order {
+		e, changed, err := bindEdit(n, wanted[n])
+		if err != nil {
+			return nil, bs, err
+		}
+		if changed {
+			edits = append(edits, e)
+		}
+	}
+
+	// Apply edits from the bottom up, so that line offsets remain valid
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	lines := strings.SplitAfter(string(in), "\n")
+	for _, e := range edits {
+		lines = append(lines[:e.start], append(e.lines, lines[e.end:]...)...)
+	}
+	return []byte(strings.Join(lines, "")), rest, nil
+}
+
+// bindEdit returns the edit which adds bindings to a campground mapping node
+func bindEdit(cg *yaml.Node, bs []Binding) (edit, bool, error) {
+	if cg.Style&yaml.FlowStyle != 0 {
+		return edit{}, false, fmt.Errorf("line %d: flow style campgrounds can not be edited", cg.Line)
+	}
+
+	ids := map[string][]string{}
+	k, v := mappingValue(cg, "provider_ids")
+	if v != nil {
+		if err := v.Decode(&ids); err != nil {
+			return edit{}, false, fmt.Errorf("line %d: provider_ids: %w", v.Line, err)
+		}
+	}
+
+	changed := false
+	for _, b := range bs {
+		id := strings.TrimSpace(b.ID)
+		found := false
+		for _, x := range ids[b.Provider] {
+			if x == id {
+				found = true
+			}
+		}
+		if !found {
+			ids[b.Provider] = append(ids[b.Provider], id)
+			changed = true
+		}
+	}
+	if !changed {
+		return edit{}, false, nil
+	}
+
+	var e edit
+	switch rk, _ := mappingValue(cg, "refs"); {
+	case k != nil:
+		e = edit{start: k.Line - 1, end: lastLine(v)}
+	case rk != nil:
+		// provider_ids are kept ahead of refs, as they would be when marshaled
+		e = edit{start: rk.Line - 1, end: rk.Line - 1}
+	default:
+		e = edit{start: lastLine(cg), end: lastLine(cg)}
+	}
+
+	indent := cg.Content[0].Column - 1
+	lines, err := render(map[string]map[string][]string{"provider_ids": ids}, indent)
+	if err != nil {
+		return edit{}, false, err
+	}
+	e.lines = lines
+	return e, true, nil
+}
+
+// render renders a value as YAML lines at the given indentation
+func render(v interface{}, indent int) ([]string, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(4)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+
+	lines := strings.SplitAfter(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.Repeat(" ", indent) + strings.TrimSuffix(l, "\n") + "\n"
+	}
+	return lines, nil
+}
+
+// campgroundNodes returns campground mapping nodes by property and campground ID
+func campgroundNodes(doc *yaml.Node) map[string]*yaml.Node {
+	cgs := map[string]*yaml.Node{}
+	if len(doc.Content) == 0 {
+		return cgs
+	}
+
+	_, props := mappingValue(doc.Content[0], "properties")
+	if props == nil {
+		return cgs
+	}
+
+	for _, p := range props.Content {
+		_, pid := mappingValue(p, "id")
+		_, cs := mappingValue(p, "campgrounds")
+		if pid == nil || cs == nil {
+			continue
+		}
+		for _, c := range cs.Content {
+			if _, cid := mappingValue(c, "id"); cid != nil {
+				cgs[pid.Value+"/"+cid.Value] = c
+			}
+		}
+	}
+	return cgs
+}
+
+// mappingValue returns the key and value nodes for a key within a mapping node
+func mappingValue(m *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if m.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i], m.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// lastLine returns the last line number that a node or its children appear on
+func lastLine(n *yaml.Node) int {
+	l := n.Line
+	for _, c := range n.Content {
+		if cl := lastLine(c); cl > l {
+			l = cl
+		}
+	}
+	return l
+}
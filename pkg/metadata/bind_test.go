@@ -0,0 +1,158 @@
+package metadata
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const bindYAML = `properties:
+    - id: /ca/angeles
+      name: Angeles National Forest
+      campgrounds:
+        - id: table_mountain
+          name: Table Mountain
+          res_url: http://www.recreation.gov
+          refs:
+            cc:
+                name: Table Mountain
+                rating: 6
+        - id: lake_campground
+          name: Lake Campground
+          provider_ids:
+            ramerica: [PRCG_1060800]
+          refs:
+            cc:
+                rating: 8
+    # hand-edited
+    - {id: /ca/coyote, name: Coyote Lake,
+       campgrounds: [{id: coyote, name: Coyote Lake}]}
+    - id: /ca/bare
+      campgrounds:
+        - id: bare
+          name: Bare
+`
+
+func TestBind(t *testing.T) {
+	var tests = []struct {
+		name     string
+		bindings []Binding
+		want     string
+		wantRest []Binding
+	}{
+		{
+			name:     "none",
+			bindings: nil,
+			want:     bindYAML,
+			wantRest: []Binding{},
+		},
+		{
+			name: "insert before refs",
+			bindings: []Binding{
+				{PropertyID: "/ca/angeles", CampgroundID: "table_mountain", Provider: "rcalifornia", ID: " 712 "},
+			},
+			want: `properties:
+    - id: /ca/angeles
+      name: Angeles National Forest
+      campgrounds:
+        - id: table_mountain
+          name: Table Mountain
+          res_url: http://www.recreation.gov
+          provider_ids:
+              rcalifornia:
+                  - "712"
+          refs:
+            cc:
+                name: Table Mountain
+                rating: 6
+        - id: lake_campground
+          name: Lake Campground
+          provider_ids:
+            ramerica: [PRCG_1060800]
+          refs:
+            cc:
+                rating: 8
+    # hand-edited
+    - {id: /ca/coyote, name: Coyote Lake,
+       campgrounds: [{id: coyote, name: Coyote Lake}]}
+    - id: /ca/bare
+      campgrounds:
+        - id: bare
+          name: Bare
+`,
+			wantRest: []Binding{},
+		},
+		{
+			name: "merge existing and append",
+			bindings: []Binding{
+				{PropertyID: "/ca/angeles", CampgroundID: "lake_campground", Provider: "ramerica", ID: "PRCG_1060801"},
+				{PropertyID: "/ca/bare", CampgroundID: "bare", Provider: "scc", ID: "bare-1"},
+			},
+			want: `properties:
+    - id: /ca/angeles
+      name: Angeles National Forest
+      campgrounds:
+        - id: table_mountain
+          name: Table Mountain
+          res_url: http://www.recreation.gov
+          refs:
+            cc:
+                name: Table Mountain
+                rating: 6
+        - id: lake_campground
+          name: Lake Campground
+          provider_ids:
+              ramerica:
+                  - PRCG_1060800
+                  - PRCG_1060801
+          refs:
+            cc:
+                rating: 8
+    # hand-edited
+    - {id: /ca/coyote, name: Coyote Lake,
+       campgrounds: [{id: coyote, name: Coyote Lake}]}
+    - id: /ca/bare
+      campgrounds:
+        - id: bare
+          name: Bare
+          provider_ids:
+              scc:
+                  - bare-1
+`,
+			wantRest: []Binding{},
+		},
+		{
+			name: "already bound and unknown",
+			bindings: []Binding{
+				{PropertyID: "/ca/angeles", CampgroundID: "lake_campground", Provider: "ramerica", ID: "PRCG_1060800"},
+				{PropertyID: "/ca/elsewhere", CampgroundID: "x", Provider: "scc", ID: "1"},
+			},
+			want: bindYAML,
+			wantRest: []Binding{
+				{PropertyID: "/ca/elsewhere", CampgroundID: "x", Provider: "scc", ID: "1"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, rest, err := bind([]byte(bindYAML), tc.bindings)
+			if err != nil {
+				t.Fatalf("bind: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, string(got)); diff != "" {
+				t.Errorf("bind() unexpected diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantRest, rest); diff != "" {
+				t.Errorf("bind() rest unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBindFlowStyle(t *testing.T) {
+	bs := []Binding{{PropertyID: "/ca/coyote", CampgroundID: "coyote", Provider: "scc", ID: "1"}}
+	if _, _, err := bind([]byte(bindYAML), bs); err == nil {
+		t.Errorf("bind() of a flow style campground succeeded, want error")
+	}
+}
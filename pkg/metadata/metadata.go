@@ -18,6 +18,9 @@ import (
 var (
 	CompressHeader = `H4sIAAAAAAAA/`
 	CompressPrefix = `z`
+
+	// files are the metadata files which are loaded, relative to the source root
+	files = []string{"metadata/srcs.yaml", "metadata/ca.yaml"}
 )
 
 // LoadAll returns all cross-reference data
@@ -25,7 +28,7 @@ func LoadAll() (map[string]campwiz.Source, map[string]*campwiz.Property, error)
 	csrcs := map[string]campwiz.Source{}
 	cprops := map[string]*campwiz.Property{}
 
-	for _, p := range []string{"metadata/srcs.yaml", "metadata/ca.yaml"} {
+	for _, p := range files {
 		path := relpath.Find(p)
 		if path == "" {
 			klog.Errorf("unable to find %s", p)
@@ -67,29 +70,35 @@ func loadPath(path string) (map[string]campwiz.Source, map[string]*campwiz.Prope
 
 	props := map[string]*campwiz.Property{}
 	for _, p := range ccd.Properties {
+		for _, c := range p.Campgrounds {
+			c.PropertyID = p.ID
+		}
 		props[p.ID] = p
 	}
 	return ccd.Sources, props, nil
 }
 
-func Decompress(s string) string {
-	bs, err := base64.RawStdEncoding.DecodeString(CompressHeader + s[1:])
+// Decompress returns the text of a description compressed by Compress. Plain text which
+// merely starts with CompressPrefix, such as "zen garden", returns an error.
+func Decompress(s string) (string, error) {
+	if !strings.HasPrefix(s, CompressPrefix) {
+		return "", fmt.Errorf("missing %q prefix", CompressPrefix)
+	}
+	bs, err := base64.RawStdEncoding.DecodeString(CompressHeader + s[len(CompressPrefix):])
 	if err != nil {
-		klog.Fatalf("decode fail: %v", err)
+		return "", fmt.Errorf("decode: %w", err)
 	}
 
-	buf := bytes.NewReader(bs)
-	zr, err := gzip.NewReader(buf)
+	zr, err := gzip.NewReader(bytes.NewReader(bs))
 	if err != nil {
-		klog.Fatalf("reader fail: %v", err)
+		return "", fmt.Errorf("reader: %w", err)
 	}
 
 	d, err := ioutil.ReadAll(zr)
 	if err != nil {
-		klog.Fatalf("read fail: %v", err)
+		return "", fmt.Errorf("read: %w", err)
 	}
-
-	return string(d)
+	return string(d), nil
 }
 
 func Compress(s string) string {
@@ -0,0 +1,21 @@
+package metadata
+
+import "testing"
+
+func TestDecompress(t *testing.T) {
+	want := "A shady grove by the creek."
+	got, err := Decompress(Compress(want))
+	if err != nil {
+		t.Fatalf("Decompress() error: %v", err)
+	}
+	if got != want {
+		t.Errorf("Decompress() = %q, want %q", got, want)
+	}
+
+	// Plain text must not be mistaken for a compressed description
+	for _, s := range []string{"", "Zion-style canyon", "zen garden", "zAAAAAAAAAAAAAAA"} {
+		if got, err := Decompress(s); err == nil {
+			t.Errorf("Decompress(%q) = %q, want an error", s, got)
+		}
+	}
+}
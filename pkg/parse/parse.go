@@ -0,0 +1,543 @@
+// Package parse turns a short description of a trip, such as
+// "tent near Big Sur next 3 weekends 2 nights rating>7 no rv", into a query
+package parse
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+const dateFormat = "2006-01-02"
+
+var (
+	// ratingRe matches a rating comparison, so that "rating > 7" reads the same as "rating>7"
+	ratingRe = regexp.MustCompile(`(?i)\b(rating|ratings|rated)\s*(>=|>|=|:)\s*`)
+	// limitRe matches a limit, so that "< 100mi" reads the same as "<100mi"
+	limitRe = regexp.MustCompile(`(^|\s)<\s+`)
+	// coordinateRe matches coordinates split by a space, as in "37.77, -122.42"
+	coordinateRe = regexp.MustCompile(`(\d),\s+([-\d])`)
+	// measureRe matches a number with a unit attached, as in "100mi" or "2h30m"
+	measureRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)([a-z]+)$`)
+	// hoursRe matches a drive time in hours and minutes, as in "2h30m"
+	hoursRe = regexp.MustCompile(`^(\d+)h(\d+)m$`)
+	// ordinalRe matches the day of a month, as in "4" or "4th"
+	ordinalRe = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+)
+
+// numbers are the numbers which may be spelled out
+var numbers = map[string]float64{
+	"one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10,
+}
+
+// fillers are words which only make a description read naturally, as in "within a 2 hour drive"
+var fillers = map[string]bool{
+	"a": true, "an": true, "and": true, "away": true, "drive": true, "driving": true,
+	"for": true, "of": true, "the": true, "under": true, "with": true, "within": true,
+}
+
+// abbreviations are the short names of days of the week, which are only read as days after a dateWord
+var abbreviations = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// dateWords are words which introduce a date, as in "on sat", "next friday" or "fri through sun"
+var dateWords = map[string]bool{
+	"on": true, "this": true, "next": true, "through": true, "thru": true, "until": true,
+}
+
+// rangeWords are the dateWords which end a range of dates, and so also follow a date
+var rangeWords = map[string]bool{"through": true, "thru": true, "until": true}
+
+// units are what a number may measure, by the words which follow it
+var units = map[string]string{
+	"mi": "miles", "mile": "miles", "miles": "miles",
+	"h": "hours", "hr": "hours", "hrs": "hours", "hour": "hours", "hours": "hours",
+	"min": "minutes", "mins": "minutes", "minute": "minutes", "minutes": "minutes",
+	"night": "nights", "nights": "nights",
+}
+
+// maxNights is the longest stay which may be searched for
+const maxNights = 14
+
+type parser struct {
+	ts  []string
+	i   int
+	now time.Time
+
+	q        campwiz.Query
+	dates    []time.Time
+	keywords []string
+	located  bool
+}
+
+// Query returns base with the parts of the trip described by s replaced: the dates, how many
+// nights, kinds of site, minimum rating, distance or drive time, location, and keywords. Words
+// it does not recognize are keywords. Relative dates, such as "next weekend", are from now.
+func Query(s string, base campwiz.Query, now time.Time) (campwiz.Query, error) {
+	s = ratingRe.ReplaceAllString(s, "$1$2")
+	s = limitRe.ReplaceAllString(s, "$1<")
+	s = coordinateRe.ReplaceAllString(s, "$1,$2")
+
+	ts, err := tokenize(s)
+	if err != nil {
+		return base, err
+	}
+
+	p := &parser{ts: ts, now: now, q: base}
+	for p.i < len(p.ts) {
+		if err := p.next(); err != nil {
+			return base, err
+		}
+	}
+
+	if p.located && (len(base.Origins) > 0 || len(base.Route) > 0) {
+		return base, fmt.Errorf("a location can not be combined with origins or a route")
+	}
+	if len(p.dates) > 0 {
+		sort.Slice(p.dates, func(i, j int) bool { return p.dates[i].Before(p.dates[j]) })
+		p.q.Dates = p.dates
+	}
+	if len(p.keywords) > 0 {
+		p.q.Keywords = p.keywords
+		if _, err := fulltext.Parse(strings.Join(p.keywords, " ")); err != nil {
+			return base, fmt.Errorf("keywords: %w", err)
+		}
+	}
+	return p.q, nil
+}
+
+// tokenize splits a description into words, keeping "quoted phrases" together
+func tokenize(s string) ([]string, error) {
+	ts := []string{}
+	rs := []rune(s)
+	for i := 0; i < len(rs); {
+		if rs[i] == ' ' || rs[i] == '\t' || rs[i] == '\n' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(rs) && rs[i] != ' ' && rs[i] != '\t' && rs[i] != '\n' {
+			if rs[i] == '"' {
+				end := i + 1
+				for end < len(rs) && rs[end] != '"' {
+					end++
+				}
+				if end == len(rs) {
+					return nil, fmt.Errorf("unterminated quote in %q", s)
+				}
+				i = end
+			}
+			i++
+		}
+		ts = append(ts, string(rs[start:i]))
+	}
+	return ts, nil
+}
+
+// peek returns the word n after the current one, lower-cased, or "" past the end
+func (p *parser) peek(n int) string {
+	if p.i+n >= len(p.ts) {
+		return ""
+	}
+	return strings.ToLower(p.ts[p.i+n])
+}
+
+// next parses the phrase starting at the current word
+func (p *parser) next() error {
+	w := p.peek(0)
+
+	switch {
+	case fillers[w]:
+		p.i++
+		return nil
+	case w == "near" || w == "around":
+		return p.location()
+	case w == "no" || w == "without":
+		return p.exclude()
+	case dateWords[w]:
+		return p.relative()
+	case strings.HasPrefix(w, "rating") || strings.HasPrefix(w, "rated"):
+		return p.rating()
+	case w == "today" || w == "tonight":
+		p.dates = append(p.dates, p.today())
+		p.i++
+		return nil
+	case w == "tomorrow":
+		p.dates = append(p.dates, p.today().AddDate(0, 0, 1))
+		p.i++
+		return nil
+	}
+
+	if d, err := time.Parse(dateFormat, w); err == nil {
+		p.dates = append(p.dates, d)
+		p.i++
+		return nil
+	}
+	if d, ok := p.weekday(w, rangeWords[p.peek(1)]); ok {
+		p.dates = append(p.dates, d)
+		p.i++
+		return nil
+	}
+	if d, ok, err := p.monthDay(w, p.peek(1)); err != nil {
+		return err
+	} else if ok {
+		p.dates = append(p.dates, d)
+		p.i += 2
+		return nil
+	}
+	if ks, err := campwiz.ParseSiteKinds(w); err == nil {
+		p.q.SiteKinds = appendKinds(p.q.SiteKinds, ks)
+		p.i++
+		return nil
+	}
+	if strings.HasPrefix(w, "<") {
+		return p.measure(w[1:], true)
+	}
+	if _, ok := number(w); ok || measureRe.MatchString(w) || hoursRe.MatchString(w) {
+		return p.measure(w, false)
+	}
+
+	p.keyword()
+	return nil
+}
+
+// keyword adds the current word to the keywords
+func (p *parser) keyword() {
+	p.keywords = append(p.keywords, p.ts[p.i])
+	p.i++
+}
+
+// location parses "near Big Sur" or "near 36.27,-121.81"
+func (p *parser) location() error {
+	near := p.peek(0)
+	p.i++
+	if p.peek(0) == "" {
+		return fmt.Errorf("%q: want a place, such as %q or %q", near, "near Big Sur", "near 36.27,-121.81")
+	}
+
+	if strings.Contains(p.peek(0), ",") {
+		o, err := campwiz.ParseOrigin(p.peek(0))
+		if err != nil {
+			return fmt.Errorf("%s %s: want latitude,longitude, such as %q", near, p.ts[p.i], "near 36.27,-121.81")
+		}
+		p.q.Lat, p.q.Lon = o.Lat, o.Lon
+		p.located = true
+		p.i++
+		return nil
+	}
+
+	// Prefer the longest place name, so that "south lake tahoe" is not read as "south" and "lake tahoe"
+	for n := 4; n > 0; n-- {
+		if p.i+n > len(p.ts) {
+			continue
+		}
+		name := strings.Trim(strings.Join(p.ts[p.i:p.i+n], " "), `"`)
+		if pt, ok := geo.Lookup(name); ok {
+			p.q.Lat, p.q.Lon = pt.Lat, pt.Lon
+			p.located = true
+			p.i += n
+			return nil
+		}
+	}
+	return fmt.Errorf("%s %s: unknown place, want latitude,longitude or one of: %s", near, p.ts[p.i], strings.Join(geo.PlaceNames(), ", "))
+}
+
+// exclude parses "no rv", excluding a kind of site, or "no dogs", excluding a keyword
+func (p *parser) exclude() error {
+	no := p.peek(0)
+	w := p.peek(1)
+	if w == "" {
+		return fmt.Errorf("%q: want something to exclude, such as %q", no, "no rv")
+	}
+
+	if ks, err := campwiz.ParseSiteKinds(w); err == nil {
+		p.q.ExcludeKinds = appendKinds(p.q.ExcludeKinds, ks)
+	} else {
+		p.keywords = append(p.keywords, "-"+p.ts[p.i+1])
+	}
+	p.i += 2
+	return nil
+}
+
+// relative parses a day after a word which introduces it, such as "on sat", "next friday" or
+// "fri through sun", or "this weekend" and "next 3 weekends". Otherwise the word is a keyword.
+func (p *parser) relative() error {
+	w := p.peek(0)
+	if d, ok := p.weekday(p.peek(1), true); ok {
+		if rangeWords[w] {
+			p.through(d)
+		} else {
+			p.dates = append(p.dates, d)
+		}
+		p.i += 2
+		return nil
+	}
+
+	if w == "this" || w == "next" {
+		unit := p.peek(1)
+		if _, ok := number(unit); ok && w == "next" {
+			unit = p.peek(2)
+		}
+		if unit == "weekend" || unit == "weekends" {
+			return p.weekends()
+		}
+	}
+
+	p.keyword()
+	return nil
+}
+
+// through adds every date after the latest one, or from today, through d
+func (p *parser) through(d time.Time) {
+	start := p.today()
+	for _, x := range p.dates {
+		if !x.Before(start) {
+			start = x.AddDate(0, 0, 1)
+		}
+	}
+	for d.Before(start) {
+		d = d.AddDate(0, 0, 7)
+	}
+	for x := start; !x.After(d); x = x.AddDate(0, 0, 1) {
+		p.dates = append(p.dates, x)
+	}
+}
+
+// weekends parses "this weekend", "next weekend", or "next 3 weekends". A weekend is searched
+// from Friday night, and the next weekend is the coming one.
+func (p *parser) weekends() error {
+	phrase := strings.Join(p.ts[p.i:minInt(p.i+3, len(p.ts))], " ")
+	count := 1.0
+	used := 2
+	if n, ok := number(p.peek(1)); ok && p.peek(0) == "next" {
+		count = n
+		used = 3
+	}
+
+	if count < 1 || count > 52 || count != math.Trunc(count) {
+		return fmt.Errorf("%q: want from 1 to 52 weekends", phrase)
+	}
+
+	friday := p.today()
+	for friday.Weekday() != time.Friday {
+		friday = friday.AddDate(0, 0, 1)
+	}
+	for n := 0; n < int(count); n++ {
+		p.dates = append(p.dates, friday.AddDate(0, 0, 7*n))
+	}
+	p.i += used
+	return nil
+}
+
+// rating parses a minimum rating, such as "rating>7", "rating>=7", "rating:7" or "rating 7+".
+// Without a rating to follow it, as in "top rated", the word is a keyword.
+func (p *parser) rating() error {
+	w := p.peek(0)
+	rest := strings.TrimLeft(strings.TrimPrefix(strings.TrimPrefix(w, "rating"), "rated"), "s")
+	used := 1
+	if rest == "" {
+		if _, err := strconv.ParseFloat(strings.TrimSuffix(p.peek(1), "+"), 64); err != nil {
+			p.keyword()
+			return nil
+		}
+		rest = p.peek(1)
+		used = 2
+	}
+	phrase := strings.Join(p.ts[p.i:p.i+used], " ")
+
+	strict := false
+	switch {
+	case strings.HasPrefix(rest, ">="):
+		rest = rest[2:]
+	case strings.HasPrefix(rest, ">"):
+		rest = rest[1:]
+		strict = true
+	case strings.HasPrefix(rest, ":"), strings.HasPrefix(rest, "="):
+		rest = rest[1:]
+	}
+	rest = strings.TrimSuffix(rest, "+")
+
+	r, err := strconv.ParseFloat(rest, 64)
+	if err != nil || r < 0 || r > campwiz.RatingScale {
+		return fmt.Errorf("%q: want a rating from 0 to %.0f, such as %q", phrase, campwiz.RatingScale, "rating>7")
+	}
+	p.q.MinRating = r
+	p.q.MinRatingExclusive = strict
+	p.i += used
+	return nil
+}
+
+// measure parses a number of nights, a distance, or a drive time, such as "2 nights",
+// "100mi", "100 miles", "2h", or "90 minutes". Limits may be written as "<100mi".
+func (p *parser) measure(w string, limit bool) error {
+	var n float64
+	var unit string
+	used := 1
+
+	if m := hoursRe.FindStringSubmatch(w); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		mins, _ := strconv.Atoi(m[2])
+		p.q.MaxDriveTime = time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute
+		p.i++
+		return nil
+	}
+
+	if m := measureRe.FindStringSubmatch(w); m != nil && units[m[2]] != "" {
+		n, _ = strconv.ParseFloat(m[1], 64)
+		unit = units[m[2]]
+	} else if v, ok := number(w); ok {
+		n = v
+		unit = units[p.peek(1)]
+		used = 2
+	}
+
+	phrase := strings.Join(p.ts[p.i:minInt(p.i+used, len(p.ts))], " ")
+	switch unit {
+	case "miles":
+		if n <= 0 {
+			return fmt.Errorf("%q: want a distance of more than 0 miles", phrase)
+		}
+		p.q.MaxDistance = int(math.Ceil(n))
+	case "hours", "minutes":
+		d := time.Duration(n * float64(time.Hour))
+		if unit == "minutes" {
+			d = time.Duration(n * float64(time.Minute))
+		}
+		if d <= 0 {
+			return fmt.Errorf("%q: want a drive time of more than 0 minutes", phrase)
+		}
+		p.q.MaxDriveTime = d
+	case "nights":
+		if limit || n < 1 || n > maxNights || n != math.Trunc(n) {
+			return fmt.Errorf("%q: want from 1 to %d nights", phrase, maxNights)
+		}
+		p.q.StayLength = int(n)
+	default:
+		// A number which does not measure anything, as in "highway 1", is only a mistake after "within"
+		if !limit && !p.limited() {
+			p.keyword()
+			return nil
+		}
+		return fmt.Errorf("%q: want a number of nights, miles or hours, such as %q, %q or %q", phrase, "2 nights", "100mi", "2h")
+	}
+	p.i += used
+	return nil
+}
+
+// today returns the date of now, in the form that dates are searched by
+func (p *parser) today() time.Time {
+	return time.Date(p.now.Year(), p.now.Month(), p.now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// limited returns whether the current word follows "within" or "under", skipping other fillers
+func (p *parser) limited() bool {
+	for i := p.i - 1; i >= 0; i-- {
+		w := strings.ToLower(p.ts[i])
+		if w == "within" || w == "under" {
+			return true
+		}
+		if !fillers[w] {
+			return false
+		}
+	}
+	return false
+}
+
+// weekday returns the date of the coming day named w, such as "friday". Abbreviations, such
+// as "sat" or "sun", are also words, so are only days if abbrev is set.
+func (p *parser) weekday(w string, abbrev bool) (time.Time, bool) {
+	day, ok := abbreviations[w]
+	if !abbrev {
+		ok = false
+	}
+	for wd := time.Sunday; wd <= time.Saturday && !ok; wd++ {
+		if strings.ToLower(wd.String()) == w {
+			day, ok = wd, true
+		}
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	d := p.today()
+	for d.Weekday() != day {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d, true
+}
+
+// monthDay returns the next date named by a month and day, such as "jul 4th", and whether it is one
+func (p *parser) monthDay(month string, day string) (time.Time, bool, error) {
+	if len(month) < 3 {
+		return time.Time{}, false, nil
+	}
+	name := strings.ToUpper(month[:1]) + month[1:]
+	m, err := time.Parse("Jan", name)
+	if err != nil {
+		if m, err = time.Parse("January", name); err != nil {
+			return time.Time{}, false, nil
+		}
+	}
+
+	match := ordinalRe.FindStringSubmatch(day)
+	if match == nil {
+		return time.Time{}, false, nil
+	}
+	n, _ := strconv.Atoi(match[1])
+
+	today := p.today()
+	d := time.Date(today.Year(), m.Month(), n, 0, 0, 0, 0, time.UTC)
+	if d.Month() != m.Month() || n < 1 {
+		return time.Time{}, false, fmt.Errorf("%q: %s has no day %d", month+" "+day, m.Month(), n)
+	}
+	if d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d, true, nil
+}
+
+// number parses a number written with digits or spelled out, such as "3" or "three"
+func number(w string) (float64, bool) {
+	if n, ok := numbers[w]; ok {
+		return n, true
+	}
+	n, err := strconv.ParseFloat(w, 64)
+	return n, err == nil
+}
+
+// appendKinds returns ks with the kinds of site which are not already within it appended
+func appendKinds(ks []campwiz.SiteKind, add []campwiz.SiteKind) []campwiz.SiteKind {
+	// ks may belong to the base query, which must not be modified
+	ks = append([]campwiz.SiteKind{}, ks...)
+	for _, a := range add {
+		found := false
+		for _, k := range ks {
+			if k == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ks = append(ks, a)
+		}
+	}
+	return ks
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,165 @@
+package parse
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestQuery(t *testing.T) {
+	// A Monday afternoon
+	now := time.Date(2021, 3, 1, 15, 4, 5, 0, time.Local)
+	base := campwiz.Query{Lat: 37.4, Lon: -122.1, StayLength: 2, MaxDistance: 200, Dates: []time.Time{date("2021-02-01")}, Keywords: []string{"lake"}}
+	bigSur, _ := geo.Lookup("big sur")
+	tahoe, _ := geo.Lookup("south lake tahoe")
+	rvs := []campwiz.SiteKind{campwiz.RV, campwiz.AccessibleRV}
+
+	tests := []struct {
+		in   string
+		want campwiz.Query
+	}{
+		{in: "", want: base},
+		{
+			in: "tent near Big Sur next 3 weekends 2 nights rating>7 no rv",
+			want: campwiz.Query{
+				Lat: bigSur.Lat, Lon: bigSur.Lon, StayLength: 2, MaxDistance: 200,
+				Dates:              []time.Time{date("2021-03-05"), date("2021-03-12"), date("2021-03-19")},
+				MinRating:          7,
+				MinRatingExclusive: true,
+				SiteKinds:          []campwiz.SiteKind{campwiz.Tent},
+				ExcludeKinds:       rvs,
+				Keywords:           []string{"lake"},
+			},
+		},
+		{
+			in: `Cabins near "south lake tahoe" this weekend for three nights rating >= 8 < 2 hours "hot springs" no dogs`,
+			want: campwiz.Query{
+				Lat: tahoe.Lat, Lon: tahoe.Lon, StayLength: 3, MaxDistance: 200,
+				Dates:     []time.Time{date("2021-03-05")},
+				MinRating: 8, MaxDriveTime: 2 * time.Hour,
+				SiteKinds: []campwiz.SiteKind{campwiz.Lodging},
+				Keywords:  []string{`"hot springs"`, "-dogs"},
+			},
+		},
+		{
+			in: "rvs near 36.27, -121.81 within 100 miles tomorrow 2021-03-10 jul 4th rating 6+",
+			want: campwiz.Query{
+				Lat: 36.27, Lon: -121.81, StayLength: 2, MaxDistance: 100,
+				Dates:     []time.Time{date("2021-03-02"), date("2021-03-10"), date("2021-07-04")},
+				MinRating: 6,
+				SiteKinds: rvs,
+				Keywords:  []string{"lake"},
+			},
+		},
+		{
+			in:   "within a 2 hour drive saturday 1 night beach",
+			want: campwiz.Query{Lat: 37.4, Lon: -122.1, StayLength: 1, MaxDistance: 200, MaxDriveTime: 2 * time.Hour, Dates: []time.Time{date("2021-03-06")}, Keywords: []string{"beach"}},
+		},
+		{
+			in:   "<2h30m <50mi feb 2 tonight",
+			want: campwiz.Query{Lat: 37.4, Lon: -122.1, StayLength: 2, MaxDistance: 50, MaxDriveTime: 150 * time.Minute, Dates: []time.Time{date("2021-03-01"), date("2022-02-02")}, Keywords: []string{"lake"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := Query(tc.in, base, now)
+			if err != nil {
+				t.Fatalf("Query(%q) unexpected error: %v", tc.in, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Query(%q) unexpected diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
+
+// TestQueryWords verifies that days are only read from words which can not be keywords
+// or follow a word which introduces a date, and that other words are keywords
+func TestQueryWords(t *testing.T) {
+	// A Monday afternoon
+	now := time.Date(2021, 3, 1, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		in        string
+		wantDates []time.Time
+		wantWords []string
+	}{
+		{in: "redwoods sun", wantWords: []string{"redwoods", "sun"}},
+		{in: "sat wed thu mon", wantWords: []string{"sat", "wed", "thu", "mon"}},
+		{in: "sunday", wantDates: []time.Time{date("2021-03-07")}},
+		{in: "beach on sat", wantDates: []time.Time{date("2021-03-06")}, wantWords: []string{"beach"}},
+		{in: "next fri", wantDates: []time.Time{date("2021-03-05")}},
+		{in: "fri through sun", wantDates: []time.Time{date("2021-03-05"), date("2021-03-06"), date("2021-03-07")}},
+		{in: "next to the lake", wantWords: []string{"next", "to", "lake"}},
+		{in: "next month", wantWords: []string{"next", "month"}},
+		{in: "on the river", wantWords: []string{"on", "river"}},
+		{in: "highway 1", wantWords: []string{"highway", "1"}},
+		{in: "4wd trail", wantWords: []string{"4wd", "trail"}},
+		{in: "top rated", wantWords: []string{"top", "rated"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := Query(tc.in, campwiz.Query{}, now)
+			if err != nil {
+				t.Fatalf("Query(%q) unexpected error: %v", tc.in, err)
+			}
+			if diff := cmp.Diff(tc.wantDates, got.Dates, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Query(%q) unexpected dates (-want +got):\n%s", tc.in, diff)
+			}
+			if diff := cmp.Diff(tc.wantWords, got.Keywords, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Query(%q) unexpected keywords (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	now := time.Date(2021, 3, 1, 15, 4, 5, 0, time.Local)
+	tests := []struct {
+		in   string
+		base campwiz.Query
+		// want is part of the error, which should say what was expected
+		want string
+	}{
+		{in: "near Atlantis", want: "unknown place, want latitude,longitude or one of: big sur"},
+		{in: "near", want: "want a place"},
+		{in: "near 97,-121", want: "want latitude,longitude"},
+		{in: "next 0 weekends", want: "want from 1 to 52 weekends"},
+		{in: "rating>11", want: "want a rating from 0 to 10"},
+		{in: "rating>", want: "want a rating"},
+		{in: "30 nights", want: "want from 1 to 14 nights"},
+		{in: "within 100", want: `want a number of nights, miles or hours, such as "2 nights"`},
+		{in: "within 90m", want: "want a number of nights, miles or hours"},
+		{in: "feb 30", want: "February has no day 30"},
+		{in: "no", want: "want something to exclude"},
+		{in: `"hot springs`, want: "unterminated quote"},
+		{in: "lake -", want: "keywords: nothing to exclude"},
+		{in: "near big sur", base: campwiz.Query{Origins: []campwiz.Origin{{Name: "home", Lat: 37, Lon: -122}}}, want: "can not be combined"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := Query(tc.in, tc.base, now)
+			if err == nil {
+				t.Fatalf("Query(%q) = %+v, want an error", tc.in, got)
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Query(%q) error = %q, want it to contain %q", tc.in, err, tc.want)
+			}
+		})
+	}
+}
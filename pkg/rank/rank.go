@@ -0,0 +1,230 @@
+// Package rank orders search results using named strategies
+package rank
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+)
+
+// Default is the name of the strategy used when none is given
+const Default = "rating"
+
+// Route is the name of the strategy which orders results along the route of a query
+const Route = "route"
+
+// Strategy is a named way of ordering results
+type Strategy struct {
+	Name string
+	Desc string
+	// Score returns how highly a result should be ranked: higher scores come first
+	Score func(r campwiz.Result) float64
+}
+
+// Weights are how much each signal counts towards a composite score, by signal name
+type Weights map[string]float64
+
+// DefaultWeights are the composite weights used when none are given
+var DefaultWeights = Weights{
+	"rating":       3,
+	"distance":     2,
+	"availability": 1,
+	"awards":       1,
+	"spots":        0.5,
+}
+
+// signals score an aspect of a result from 0 to 1
+var signals = map[string]func(r campwiz.Result) float64{
+	"rating":       func(r campwiz.Result) float64 { return r.Rating / campwiz.RatingScale },
+	"distance":     func(r campwiz.Result) float64 { return 1 / (1 + r.Distance/100) },
+	"availability": func(r campwiz.Result) float64 { return saturate(float64(dates(r))) },
+	"awards":       awards,
+	"spots":        func(r campwiz.Result) float64 { return saturate(float64(spots(r))) },
+}
+
+// Names returns the names of every strategy
+func Names() []string {
+	return []string{"rating", "distance", "available", "value", "composite", Route}
+}
+
+// New returns the named strategy. Weights are only used by the composite strategy.
+func New(name string, ws Weights) (Strategy, error) {
+	switch name {
+	case "", "rating":
+		return Strategy{Name: "rating", Desc: "best rated", Score: func(r campwiz.Result) float64 { return r.Rating }}, nil
+	case "distance":
+		return Strategy{Name: "distance", Desc: "closest", Score: func(r campwiz.Result) float64 { return -r.Distance }}, nil
+	case "available":
+		return Strategy{
+			Name: "available",
+			Desc: "most available",
+			// Dates available outweigh any number of spots on fewer dates
+			Score: func(r campwiz.Result) float64 { return float64(dates(r)) + saturate(float64(spots(r))) },
+		}, nil
+	case "value":
+		return Strategy{
+			Name:  "value",
+			Desc:  "best value: rating for the distance travelled",
+			Score: func(r campwiz.Result) float64 { return r.Rating * signals["distance"](r) },
+		}, nil
+	case Route:
+		return Strategy{Name: Route, Desc: "in order along the route", Score: func(r campwiz.Result) float64 { return -r.RouteMiles }}, nil
+	case "composite":
+		if ws == nil {
+			ws = DefaultWeights
+		}
+		if err := ws.validate(); err != nil {
+			return Strategy{}, err
+		}
+		return Strategy{Name: "composite", Desc: fmt.Sprintf("composite score of %s", ws), Score: ws.score}, nil
+	}
+	return Strategy{}, fmt.Errorf("unknown sort %q, expected one of %s", name, strings.Join(Names(), ", "))
+}
+
+// Sort orders results by a strategy. Results with an equal score are ordered by rating,
+// distance, name, provider, and ID, so that the order never depends on the input order.
+func Sort(rs []campwiz.Result, s Strategy) {
+	b := byScore{rs: rs, scores: make([]float64, len(rs))}
+	for i, r := range rs {
+		b.scores[i] = s.Score(r)
+	}
+	sort.Sort(b)
+}
+
+// byScore sorts results and their precomputed scores together
+type byScore struct {
+	rs     []campwiz.Result
+	scores []float64
+}
+
+func (b byScore) Len() int { return len(b.rs) }
+
+func (b byScore) Swap(i, j int) {
+	b.rs[i], b.rs[j] = b.rs[j], b.rs[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
+
+func (b byScore) Less(i, j int) bool {
+	x, y := b.rs[i], b.rs[j]
+	switch {
+	case b.scores[i] != b.scores[j]:
+		return b.scores[i] > b.scores[j]
+	case x.Rating != y.Rating:
+		return x.Rating > y.Rating
+	case x.Distance != y.Distance:
+		return x.Distance < y.Distance
+	case x.Name != y.Name:
+		return x.Name < y.Name
+	case x.Provider != y.Provider:
+		return x.Provider < y.Provider
+	}
+	return x.ResID < y.ResID
+}
+
+// ParseWeights parses composite weights in the form of "rating=3,distance=1"
+func ParseWeights(s string) (Weights, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	ws := Weights{}
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("weight %q is not in the form of signal=weight", kv)
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("weight %q: %w", kv, err)
+		}
+		ws[strings.TrimSpace(parts[0])] = w
+	}
+	return ws, ws.validate()
+}
+
+// String returns weights in the form accepted by ParseWeights
+func (ws Weights) String() string {
+	ks := []string{}
+	for k := range ws {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	ss := []string{}
+	for _, k := range ks {
+		ss = append(ss, fmt.Sprintf("%s=%g", k, ws[k]))
+	}
+	return strings.Join(ss, ",")
+}
+
+// validate checks that weights refer to known signals
+func (ws Weights) validate() error {
+	total := 0.0
+	for k, w := range ws {
+		if signals[k] == nil {
+			return fmt.Errorf("unknown signal %q, expected one of %s", k, DefaultWeights)
+		}
+		if w < 0 {
+			return fmt.Errorf("weight for %q must not be negative", k)
+		}
+		total += w
+	}
+	if total == 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	return nil
+}
+
+// score returns the weighted average of signals, from 0 to 1
+func (ws Weights) score(r campwiz.Result) float64 {
+	total := 0.0
+	weight := 0.0
+	for _, k := range []string{"rating", "distance", "availability", "awards", "spots"} {
+		total += ws[k] * signals[k](r)
+		weight += ws[k]
+	}
+	return total / weight
+}
+
+// saturate maps a count to 0 to 1, with diminishing returns
+func saturate(n float64) float64 {
+	return n / (n + 1)
+}
+
+// dates returns the number of distinct dates a result is available on
+func dates(r campwiz.Result) int {
+	seen := map[string]bool{}
+	for _, a := range r.Availability {
+		seen[a.Date.Format("2006-01-02")] = true
+	}
+	return len(seen)
+}
+
+// spots returns the number of spots available across every date
+func spots(r campwiz.Result) int {
+	n := 0
+	for _, a := range r.Availability {
+		n += a.SpotCount
+	}
+	return n
+}
+
+// awards scores the best placement of a result within award lists
+func awards(r campwiz.Result) float64 {
+	if r.KnownCampground == nil {
+		return 0
+	}
+
+	best := 0.0
+	for _, ref := range r.KnownCampground.Refs {
+		for _, l := range ref.Lists {
+			if l.Place > 0 && 1/float64(l.Place) > best {
+				best = 1 / float64(l.Place)
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,123 @@
+package rank
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+)
+
+func avail(days int, spots int) []campwiz.Availability {
+	as := []campwiz.Availability{}
+	for i := 0; i < days; i++ {
+		as = append(as, campwiz.Availability{Date: time.Date(2021, 3, 5+i, 0, 0, 0, 0, time.UTC), SpotCount: spots})
+	}
+	return as
+}
+
+func names(rs []campwiz.Result) []string {
+	ns := []string{}
+	for _, r := range rs {
+		ns = append(ns, r.Name)
+	}
+	return ns
+}
+
+func TestSort(t *testing.T) {
+	awarded := &campwiz.Campground{Refs: map[string]*campwiz.Ref{"cc": {Lists: []campwiz.RefList{{Title: "Best", Place: 1}}}}}
+	results := []campwiz.Result{
+		{Name: "near", Distance: 10, Rating: 5, Availability: avail(1, 1), RouteMiles: 120},
+		{Name: "far", Distance: 250, Rating: 9, Availability: avail(1, 2), RouteMiles: 30},
+		{Name: "open", Distance: 80, Rating: 6, Availability: avail(3, 1), RouteMiles: 200},
+		{Name: "awarded", Distance: 60, Rating: 8, Availability: avail(1, 1), RouteMiles: 5, KnownCampground: awarded},
+	}
+
+	tests := []struct {
+		sort    string
+		weights Weights
+		want    []string
+	}{
+		{sort: "", want: []string{"far", "awarded", "open", "near"}},
+		{sort: "rating", want: []string{"far", "awarded", "open", "near"}},
+		{sort: "distance", want: []string{"near", "awarded", "open", "far"}},
+		{sort: "available", want: []string{"open", "far", "awarded", "near"}},
+		{sort: "route", want: []string{"awarded", "far", "near", "open"}},
+		{sort: "value", want: []string{"awarded", "near", "open", "far"}},
+		{sort: "composite", want: []string{"awarded", "far", "near", "open"}},
+		{sort: "composite", weights: Weights{"rating": 1}, want: []string{"far", "awarded", "open", "near"}},
+		{sort: "composite", weights: Weights{"availability": 1}, want: []string{"open", "far", "awarded", "near"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.sort+tc.weights.String(), func(t *testing.T) {
+			s, err := New(tc.sort, tc.weights)
+			if err != nil {
+				t.Fatalf("New(%q): %v", tc.sort, err)
+			}
+			rs := append([]campwiz.Result{}, results...)
+			Sort(rs, s)
+			if diff := cmp.Diff(tc.want, names(rs)); diff != "" {
+				t.Errorf("Sort() unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSortTieBreak(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "b", Provider: "scc", Rating: 7, Distance: 20},
+		{Name: "a", Provider: "smc", Rating: 7, Distance: 20},
+		{Name: "a", Provider: "scc", Rating: 7, Distance: 20, ResID: "2"},
+		{Name: "a", Provider: "scc", Rating: 7, Distance: 20, ResID: "1"},
+		{Name: "c", Provider: "scc", Rating: 7, Distance: 10},
+	}
+	want := []campwiz.Result{in[4], in[3], in[2], in[1], in[0]}
+
+	s, _ := New("rating", nil)
+	for i := 0; i < len(in); i++ {
+		// Every rotation of the input should produce the same order
+		rs := append(append([]campwiz.Result{}, in[i:]...), in[:i]...)
+		Sort(rs, s)
+		if diff := cmp.Diff(want, rs); diff != "" {
+			t.Errorf("rotation %d: Sort() unexpected diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestParseWeights(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Weights
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "rating=3, distance=1.5", want: Weights{"rating": 3, "distance": 1.5}},
+		{in: "rating", wantErr: true},
+		{in: "rating=high", wantErr: true},
+		{in: "price=1", wantErr: true},
+		{in: "rating=-1", wantErr: true},
+		{in: "rating=0", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.in, func(t *testing.T) {
+			got, err := ParseWeights(tc.in)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ParseWeights(%q) error = %v, wantErr %v", tc.in, err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ParseWeights(%q) unexpected diff (-want +got):\n%s", tc.in, diff)
+			}
+		})
+	}
+}
+
+func TestNewUnknown(t *testing.T) {
+	if _, err := New("cheapest", nil); err == nil {
+		t.Errorf("New(cheapest) succeeded, want error")
+	}
+}
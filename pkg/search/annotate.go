@@ -9,7 +9,6 @@ import (
 	"github.com/tstromberg/campwiz/pkg/mangle"
 	"github.com/tstromberg/campwiz/pkg/metadata"
 
-	"github.com/agnivade/levenshtein"
 	"k8s.io/klog/v2"
 )
 
@@ -57,32 +56,31 @@ type Match struct {
 	Campground *campwiz.Campground
 }
 
-func average(xs []float64) float64 {
-	total := 0.0
-	for _, v := range xs {
-		total += v
-	}
-	return total / float64(len(xs))
-}
-
-func annotate(r campwiz.Result, props map[string]*campwiz.Property) campwiz.Result {
-	cg := findBestMatch(r, props)
+func annotate(r campwiz.Result, idx *Index) campwiz.Result {
+	cg := findBestMatch(r, idx)
 	if cg.Score == 0 {
 		klog.Warningf("No site match for %+v", r)
 		return r
 	}
 	r.KnownCampground = cg.Campground
+	r.MatchScore = ScoreName(cg.Score)
+	r.MatchDetail = cg.Detail
 
-	ratings := []float64{}
+	// Refs are visited in a stable order, so that the same result is always annotated alike
+	keys := []string{}
+	for k := range cg.Campground.Refs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 
-	for _, ref := range cg.Campground.Refs {
-		if ref.Rating > 0 {
-			// TODO: Take into account max
-			ratings = append(ratings, ref.Rating)
-		}
+	for _, k := range keys {
+		ref := cg.Campground.Refs[k]
 		if r.Locale == "" && ref.Locale != "" {
 			r.Locale = ref.Locale
 		}
+		if r.Lat == 0 && r.Lon == 0 {
+			r.Lat, r.Lon = ref.Lat, ref.Lon
+		}
 		if r.ImageURL == "" && ref.ImageURL != "" {
 			r.ImageURL = ref.ImageURL
 		}
@@ -91,34 +89,126 @@ func annotate(r campwiz.Result, props map[string]*campwiz.Property) campwiz.Resu
 			if ref.URL != "" {
 				r.Desc = ref.Desc
 			} else {
-				r.Desc = mangle.Ellipsis(metadata.Decompress(ref.Desc), 65)
+				r.Desc = mangle.Ellipsis(refDesc(ref), 65)
 			}
 		}
 	}
 
-	r.Rating = average(ratings)
+	r.Rating, r.RatingConfidence, r.RatingSources = aggregate(cg.Campground.Refs, idx.srcs)
 	return r
 }
 
-func findBestMatch(r campwiz.Result, props map[string]*campwiz.Property) Match {
-	matches := findMatches(r, props)
+// refDesc returns the description of a ref. Descriptions are compressed unless they came from
+// a URL, but a few hand-written ones are plain text, which is returned as it is.
+func refDesc(ref *campwiz.Ref) string {
+	if ref.URL != "" || !strings.HasPrefix(ref.Desc, metadata.CompressPrefix) {
+		return ref.Desc
+	}
+	d, err := metadata.Decompress(ref.Desc)
+	if err != nil {
+		// Plain text which happens to start like a compressed description
+		klog.V(1).Infof("plain description %.20q: %v", ref.Desc, err)
+		return ref.Desc
+	}
+	return d
+}
 
-	if len(matches) == 0 {
-		return Match{Score: NoMatch}
+func findBestMatch(r campwiz.Result, idx *Index) Match {
+	// Bindings are preferred over every name heuristic
+	if m, ok := idx.Bound(r); ok {
+		return m
 	}
 
-	sort.Slice(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
-	return matches[0]
+	// A far away campground is a different one which happens to share a name
+	for _, lm := range located(r, idx) {
+		if !lm.far() {
+			return lm.Match
+		}
+	}
+	return Match{Score: NoMatch}
 }
 
-var (
-	varCache = map[string][]string{}
+const (
+	// nearMiles is how close a campground must be to a result for its location to confirm a match
+	nearMiles = 2.0
+	// nearBonus is how many scores a confirmed location is worth
+	nearBonus = 2
+	// farMiles is how far a campground may be from a result before a name match is implausible
+	farMiles = 25.0
 )
 
-func variations(s string) []string {
-	if varCache[s] != nil {
-		return varCache[s]
+// locatedMatch is a match and how far the result is from its campground, or -1 if unknown
+type locatedMatch struct {
+	Match
+	miles float64
+}
+
+// far returns whether the campground is too far from the result for a name match to be plausible
+func (m locatedMatch) far() bool {
+	return m.Score != SiteID && m.miles > farMiles
+}
+
+// rank returns the score of a match, adjusted by how far the result is from the campground
+func (m locatedMatch) rank() int {
+	switch {
+	case m.far():
+		// A far away campground ranks below any other, whatever its name
+		return m.Score - SiteID - nearBonus
+	case m.Score == SiteID || m.miles < 0:
+		return m.Score
+	case m.miles <= nearMiles:
+		return m.Score + nearBonus
+	}
+	return m.Score
+}
+
+// before returns whether a match should be ranked ahead of another, closest first within a rank
+func (m locatedMatch) before(o locatedMatch) bool {
+	if m.rank() != o.rank() {
+		return m.rank() > o.rank()
+	}
+	if m.miles >= 0 && o.miles >= 0 {
+		return m.miles < o.miles
+	}
+	return m.miles >= 0 && o.miles < 0
+}
+
+// Explain returns every way in which a result matches known campgrounds, best first.
+// When the result and campground locations are both known, their distance confirms
+// nearby matches, demotes far away ones, and breaks ties between equal matches.
+// Far away matches are listed, but never chosen by annotate.
+func Explain(r campwiz.Result, idx *Index) []Match {
+	matches := []Match{}
+	for _, lm := range located(r, idx) {
+		matches = append(matches, lm.Match)
+	}
+	return matches
+}
+
+// located returns the matches for a result with their distances, best first
+func located(r campwiz.Result, idx *Index) []locatedMatch {
+	lms := []locatedMatch{}
+	for _, m := range idx.Matches(r) {
+		lm := locatedMatch{Match: m, miles: idx.miles(r, m.Campground)}
+		if lm.miles >= 0 {
+			lm.Detail = fmt.Sprintf("%s, %.1fmi away", lm.Detail, lm.miles)
+		}
+		lms = append(lms, lm)
+	}
+	sort.SliceStable(lms, func(i, j int) bool { return lms[i].before(lms[j]) })
+	return lms
+}
+
+// ScoreName returns the name of a match score
+func ScoreName(score int) string {
+	if n, ok := scoreNames[score]; ok {
+		return n
 	}
+	return fmt.Sprintf("Score(%d)", score)
+}
+
+// variations returns alternate forms of a normalized name
+func variations(s string) []string {
 	try := map[string]bool{
 		strings.ToLower(strings.Join(strings.Split(mangle.Shortest(mangle.Expand(s)), " "), "")): true,
 		strings.ToLower(mangle.Shortest(s)):                true,
@@ -130,116 +220,8 @@ func variations(s string) []string {
 	for k, _ := range try {
 		vs = append(vs, k)
 	}
+	sort.Strings(vs)
 
-	klog.Infof("variations for %q: %v", s, vs)
-	varCache[s] = vs
-	return varCache[s]
-}
-
-func findMatches(r campwiz.Result, props map[string]*campwiz.Property) []Match {
-	var matches []Match
-	resName := mangle.Normalize(r.Name)
-
-	for _, prop := range props {
-		propName := mangle.Normalize(prop.Name)
-		// TODO: A better job guessing which campsite to use
-		var cg *campwiz.Campground
-		for _, c := range prop.Campgrounds {
-			cg = c
-		}
-
-		if resName == propName {
-			if len(prop.Campgrounds) == 1 {
-				matches = append(matches, Match{SinglePropMatch, fmt.Sprintf("result %q = single park %q", resName, prop.Name), cg})
-			} else {
-				matches = append(matches, Match{PropMatch, fmt.Sprintf("result %q = multi park %q", resName, prop.Name), cg})
-			}
-		}
-
-		for x, kv := range variations(propName) {
-			if kv == resName {
-				matches = append(matches, Match{MangledPropMatch, fmt.Sprintf("variation %d: %q = %q", x, kv, resName), cg})
-			}
-
-			for i, rv := range variations(resName) {
-				if rv == kv {
-					matches = append(matches, Match{BiMangledPropMatch, fmt.Sprintf("variation %d/%d: %q = %q", i, x, rv, propName), cg})
-				}
-
-				if strings.Contains(kv, rv) {
-					matches = append(matches, Match{BiMangledPropSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, rv, kv), cg})
-					continue
-				}
-				if strings.Contains(rv, kv) {
-					matches = append(matches, Match{BiMangledPropSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, kv, rv), cg})
-					continue
-				}
-
-				d := levenshtein.ComputeDistance(rv, kv)
-				if d < 3 {
-					matches = append(matches, Match{ApproxPropMatch, fmt.Sprintf("variation %d/%d: %q is %d edits from %q", i, x, rv, d, kv), cg})
-					continue
-				}
-			}
-
-		}
-
-		for _, cg := range prop.Campgrounds {
-			knownName := mangle.Normalize(cg.Name)
-
-			if resName == knownName {
-				matches = append(matches, Match{NameMatch, fmt.Sprintf("result %q = known %q", resName, knownName), cg})
-				continue
-			}
-
-			if strings.Contains(resName, knownName) {
-				matches = append(matches, Match{SubMatch, fmt.Sprintf("known %q in result %q", knownName, resName), cg})
-			}
-
-			if strings.Contains(knownName, resName) {
-				matches = append(matches, Match{SubMatch, fmt.Sprintf("result %q in known %q", resName, knownName), cg})
-			}
-
-			for i, rv := range variations(resName) {
-				if rv == knownName {
-					matches = append(matches, Match{MangledMatch, fmt.Sprintf("variation %d: %q = %q", i, rv, knownName), cg})
-					continue
-				}
-
-				if strings.Contains(knownName, rv) {
-					matches = append(matches, Match{MangledSubMatch, fmt.Sprintf("variation %d: result %q in known %q", i, rv, knownName), cg})
-					continue
-				}
-				if strings.Contains(rv, knownName) {
-					matches = append(matches, Match{MangledSubMatch, fmt.Sprintf("variation %d: result %q in known %q", i, knownName, rv), cg})
-					continue
-				}
-
-				for x, kv := range variations(knownName) {
-					kv = strings.ToLower(kv)
-					if rv == kv {
-						matches = append(matches, Match{BiMangledMatch, fmt.Sprintf("variation %d/%d: %q = %q", i, x, rv, knownName), cg})
-						continue
-					}
-					if strings.Contains(kv, rv) {
-						matches = append(matches, Match{BiMangledSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, rv, kv), cg})
-						continue
-					}
-					if strings.Contains(rv, kv) {
-						matches = append(matches, Match{BiMangledSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, kv, rv), cg})
-						continue
-					}
-
-					d := levenshtein.ComputeDistance(rv, kv)
-					if d < 3 {
-						matches = append(matches, Match{ApproxMatch, fmt.Sprintf("variation %d/%d: %q is %d edits from %q", i, x, rv, d, kv), cg})
-						continue
-					}
-
-				}
-			}
-		}
-	}
-
-	return matches
+	klog.V(3).Infof("variations for %q: %v", s, vs)
+	return vs
 }
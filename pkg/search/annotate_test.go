@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/metadata"
 )
 
 func TestFindBestMatch(t *testing.T) {
@@ -104,9 +105,10 @@ func TestFindBestMatch(t *testing.T) {
 		{`Joseph Grant Park`, ApproxMatch, `grant`},
 	}
 
+	idx := NewIndex(nil, props)
 	for _, tt := range tests {
 		t.Run(tt.in, func(t *testing.T) {
-			got := findBestMatch(campwiz.Result{Name: tt.in}, props)
+			got := findBestMatch(campwiz.Result{Name: tt.in}, idx)
 			if got.Score != tt.score {
 				t.Errorf("got score %d %q, want %d %q: %+v", got.Score, scoreNames[got.Score], tt.score, scoreNames[tt.score], got)
 			}
@@ -122,3 +124,229 @@ func TestFindBestMatch(t *testing.T) {
 		})
 	}
 }
+
+func TestExplain(t *testing.T) {
+	props := map[string]*campwiz.Property{
+		"/ca/angeles": {
+			ID:   "/ca/angeles",
+			Name: "Angeles National Forest",
+			Campgrounds: []*campwiz.Campground{
+				{ID: "lake_campground", Name: "Lake Campground", PropertyID: "/ca/angeles"},
+				{ID: "table_mountain", Name: "Table Mountain", PropertyID: "/ca/angeles"},
+			},
+		},
+		"/ca/cache_creek/clear_lake": {
+			ID:          "/ca/cache_creek/clear_lake",
+			Name:        "Clear Lake State Park",
+			Campgrounds: []*campwiz.Campground{{ID: "clear_lake_campground", Name: "Clear Lake Campground"}},
+		},
+	}
+
+	ms := Explain(campwiz.Result{Name: "Lake Campground"}, NewIndex(nil, props))
+	if len(ms) < 2 {
+		t.Fatalf("expected multiple candidate matches, got %+v", ms)
+	}
+	if ms[0].Score != NameMatch || ms[0].Campground.ID != "lake_campground" {
+		t.Errorf("best match = %s %+v, want NameMatch lake_campground", ScoreName(ms[0].Score), ms[0].Campground)
+	}
+	for i := 1; i < len(ms); i++ {
+		if ms[i].Score > ms[i-1].Score {
+			t.Errorf("matches are not ranked: %s (%d) follows %s (%d)", ScoreName(ms[i].Score), i, ScoreName(ms[i-1].Score), i-1)
+		}
+	}
+
+	got := annotate(campwiz.Result{Name: "Lake Campground"}, NewIndex(nil, props))
+	if got.MatchScore != "NameMatch" || got.MatchDetail == "" {
+		t.Errorf("annotate did not record match reason: %q %q", got.MatchScore, got.MatchDetail)
+	}
+}
+
+func TestBindings(t *testing.T) {
+	props := map[string]*campwiz.Property{
+		"/ca/clear_lake": {
+			ID:   "/ca/clear_lake",
+			Name: "Clear Lake State Park",
+			Campgrounds: []*campwiz.Campground{{
+				ID:          "clear_lake_campground",
+				Name:        "Clear Lake Campground",
+				ProviderIDs: map[string][]string{"rcalifornia": {"7"}},
+			}},
+		},
+		"/ca/pge": {
+			ID:   "/ca/pge",
+			Name: "PG&E Recreation",
+			Campgrounds: []*campwiz.Campground{
+				{
+					ID:          "pge_clear_lake",
+					Name:        "Lakeside",
+					ProviderIDs: map[string][]string{"ramerica": {"PRCG_1060800"}},
+				},
+				{
+					ID:     "pge_sandy",
+					Name:   "Sandy Shore",
+					ResURL: "http://www.reserveamerica.com",
+					ResID:  "PRCG_1060801",
+				},
+			},
+		},
+	}
+	idx := NewIndex(nil, props)
+
+	tests := []struct {
+		name   string
+		in     campwiz.Result
+		score  int
+		wantID string
+	}{
+		{
+			name:   "binding beats name",
+			in:     campwiz.Result{Provider: "ramerica", ResID: "PRCG_1060800", Name: "Clear Lake Campground"},
+			score:  SiteID,
+			wantID: "pge_clear_lake",
+		},
+		{
+			name:   "padded id",
+			in:     campwiz.Result{Provider: "rcalifornia", ResID: " 7", Name: "Clear Lake SP"},
+			score:  SiteID,
+			wantID: "clear_lake_campground",
+		},
+		{
+			name:   "legacy res_id",
+			in:     campwiz.Result{Provider: "ramerica", ResURL: "https://www.reserveamerica.com/explore/x", ResID: "PRCG_1060801", Name: "Sandy"},
+			score:  SiteID,
+			wantID: "pge_sandy",
+		},
+		{
+			name:   "other provider",
+			in:     campwiz.Result{Provider: "rcaliforniaAdv", ResID: "PRCG_1060800", Name: "Clear Lake Campground"},
+			score:  NameMatch,
+			wantID: "clear_lake_campground",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findBestMatch(tt.in, idx)
+			if got.Score != tt.score || got.Campground == nil || got.Campground.ID != tt.wantID {
+				t.Errorf("findBestMatch() = %s %+v, want %s %q", ScoreName(got.Score), got.Campground, ScoreName(tt.score), tt.wantID)
+			}
+			if ms := Explain(tt.in, idx); ms[0].Score != got.Score || ms[0].Campground != got.Campground {
+				t.Errorf("Explain() ranked %s %+v first, want %s", ScoreName(ms[0].Score), ms[0].Campground, ScoreName(got.Score))
+			}
+		})
+	}
+}
+
+func TestAnnotateNormalizesRatings(t *testing.T) {
+	srcs := map[string]campwiz.Source{
+		"cc":   {Name: "California Camping", RatingMax: 10},
+		"five": {Name: "Five Stars", RatingMax: 5},
+	}
+
+	tests := []struct {
+		name string
+		refs map[string]*campwiz.Ref
+		want float64
+	}{
+		{name: "ten", refs: map[string]*campwiz.Ref{"cc": {Rating: 8}}, want: 8},
+		{name: "five", refs: map[string]*campwiz.Ref{"five": {Rating: 4}}, want: 8},
+		{name: "both", refs: map[string]*campwiz.Ref{"cc": {Rating: 6}, "five": {Rating: 5}}, want: 8},
+		{name: "unknown source", refs: map[string]*campwiz.Ref{"other": {Rating: 7}}, want: 7},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			props := map[string]*campwiz.Property{
+				"/ca/lake": {
+					ID:          "/ca/lake",
+					Name:        "Lake State Park",
+					Campgrounds: []*campwiz.Campground{{ID: "lake", Name: "Lake Campground", Refs: tc.refs}},
+				},
+			}
+			got := annotate(campwiz.Result{Name: "Lake Campground"}, NewIndex(srcs, props))
+			if got.Rating != tc.want {
+				t.Errorf("annotate() rating = %.1f, want %.1f", got.Rating, tc.want)
+			}
+		})
+	}
+}
+
+func TestLocatedMatches(t *testing.T) {
+	lake := func(lat, lon float64) []*campwiz.Campground {
+		return []*campwiz.Campground{{ID: "lake_campground", Name: "Lake Campground", Refs: map[string]*campwiz.Ref{"cc": {Lat: lat, Lon: lon}}}}
+	}
+	props := map[string]*campwiz.Property{
+		"/ca/angeles":   {ID: "/ca/angeles", Name: "Angeles National Forest", Campgrounds: lake(34.33, -117.72)},
+		"/ca/sequoia":   {ID: "/ca/sequoia", Name: "Sequoia National Forest", Campgrounds: lake(35.80, -118.45)},
+		"/ca/los_padre": {ID: "/ca/los_padre", Name: "Los Padres National Forest", Campgrounds: []*campwiz.Campground{{ID: "lake_cg", Name: "Lake Camp"}}},
+	}
+	idx := NewIndex(nil, props)
+
+	tests := []struct {
+		name     string
+		in       campwiz.Result
+		wantProp string
+	}{
+		{name: "near angeles", in: campwiz.Result{Name: "Lake Campground", Lat: 34.34, Lon: -117.72}, wantProp: "/ca/angeles"},
+		{name: "near sequoia", in: campwiz.Result{Name: "Lake Campground", Lat: 35.80, Lon: -118.46}, wantProp: "/ca/sequoia"},
+		{name: "far from both", in: campwiz.Result{Name: "Lake Campground", Lat: 40.0, Lon: -122.0}, wantProp: "/ca/los_padre"},
+		{name: "between both", in: campwiz.Result{Name: "Lake Campground", Lat: 34.6, Lon: -117.9}, wantProp: "/ca/angeles"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			m := findBestMatch(tc.in, idx)
+			if m.Campground != props[tc.wantProp].Campgrounds[0] {
+				t.Errorf("findBestMatch(%+v) = %+v, want a campground in %s", tc.in, m, tc.wantProp)
+			}
+		})
+	}
+}
+
+// TestFarMatchNotLinked verifies that a result is not linked to a same-named campground elsewhere
+func TestFarMatchNotLinked(t *testing.T) {
+	far := &campwiz.Campground{ID: "lake_campground", Name: "Lake Campground", Refs: map[string]*campwiz.Ref{"cc": {Lat: 35.80, Lon: -118.45, Rating: 5}}}
+	props := map[string]*campwiz.Property{
+		"/ca/sequoia": {ID: "/ca/sequoia", Name: "Sequoia National Forest", Campgrounds: []*campwiz.Campground{far}},
+	}
+	srcs := map[string]campwiz.Source{"cc": {RatingMax: 5}}
+	idx := NewIndex(srcs, props)
+
+	// The Lake Campground within the Angeles National Forest is not indexed
+	in := campwiz.Result{Name: "Lake Campground", Lat: 34.34, Lon: -117.72}
+	if m := findBestMatch(in, idx); m.Score != NoMatch {
+		t.Errorf("findBestMatch(%+v) = %+v, want no match", in, m)
+	}
+
+	got := annotate(in, idx)
+	if got.KnownCampground != nil || got.Rating != 0 {
+		t.Errorf("annotate(%+v) linked %+v with rating %.1f, want no campground", in, got.KnownCampground, got.Rating)
+	}
+
+	if ms := Explain(in, idx); len(ms) == 0 || ms[0].Campground != far {
+		t.Errorf("Explain(%+v) = %+v, want the far campground listed", in, ms)
+	}
+}
+
+func TestRefDesc(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *campwiz.Ref
+		want string
+	}{
+		{name: "empty", in: &campwiz.Ref{}, want: ""},
+		{name: "compressed", in: &campwiz.Ref{Desc: metadata.Compress("A shady grove by the creek.")}, want: "A shady grove by the creek."},
+		{name: "plain text", in: &campwiz.Ref{Desc: "A historic hotel."}, want: "A historic hotel."},
+		{name: "plain text with the prefix", in: &campwiz.Ref{Desc: "zen garden by the lake."}, want: "zen garden by the lake."},
+		{name: "plain text like the header", in: &campwiz.Ref{Desc: "zAAAAAAAAAAAAAAA"}, want: "zAAAAAAAAAAAAAAA"},
+		{name: "from a URL", in: &campwiz.Ref{URL: "https://example.com/", Desc: "Scraped text."}, want: "Scraped text."},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := refDesc(tc.in); got != tc.want {
+				t.Errorf("refDesc() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
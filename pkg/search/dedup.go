@@ -0,0 +1,128 @@
+package search
+
+import (
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"k8s.io/klog/v2"
+)
+
+// sameSiteMiles is how close two results must be to be considered the same campground
+const sameSiteMiles = 0.1
+
+// dedup merges results from different providers which refer to the same campground,
+// either by resolving to the same known campground or by sitting at the same coordinates.
+// Park-level matches only name the park, so are merged by their coordinates instead.
+func dedup(rs []campwiz.Result) []campwiz.Result {
+	merged := []campwiz.Result{}
+	known := map[*campwiz.Campground]int{}
+
+	for _, r := range rs {
+		i, ok := known[r.KnownCampground]
+		if !identified(r) {
+			i, ok = nearby(merged, r)
+		}
+
+		// Distinct results from the same provider are distinct sites, whatever they matched
+		if ok && contains(merged[i].Providers, r.Provider) {
+			merged = append(merged, r)
+			continue
+		}
+
+		if !ok {
+			if identified(r) {
+				known[r.KnownCampground] = len(merged)
+			}
+			merged = append(merged, r)
+			continue
+		}
+
+		klog.V(1).Infof("merging %s result %q into %s result %q", r.Provider, r.Name, merged[i].Provider, merged[i].Name)
+		merged[i] = merge(merged[i], r)
+	}
+	return merged
+}
+
+// identified returns whether a result was matched to its campground by a binding or its full name,
+// rather than to whichever campground of a park it shares a name with
+func identified(r campwiz.Result) bool {
+	if r.KnownCampground == nil {
+		return false
+	}
+	for s := ApproxMatch; s <= SiteID; s++ {
+		if r.MatchScore == ScoreName(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// nearby returns the offset of a result without an identified campground at the same coordinates
+func nearby(rs []campwiz.Result, r campwiz.Result) (int, bool) {
+	if r.Lat == 0 && r.Lon == 0 {
+		return 0, false
+	}
+	for i, o := range rs {
+		if identified(o) || (o.Lat == 0 && o.Lon == 0) {
+			continue
+		}
+		if geo.MilesApart(r.Lat, r.Lon, o.Lat, o.Lon) <= sameSiteMiles {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// merge combines the availability of two results for the same campground
+func merge(a campwiz.Result, b campwiz.Result) campwiz.Result {
+	for _, p := range b.Providers {
+		if !contains(a.Providers, p) {
+			a.Providers = append(a.Providers, p)
+		}
+	}
+
+	for _, av := range b.Availability {
+		found := false
+		for _, x := range a.Availability {
+			if x.Provider == av.Provider && x.URL == av.URL && x.Date.Equal(av.Date) && x.Kind == av.Kind {
+				found = true
+			}
+		}
+		if !found {
+			a.Availability = append(a.Availability, av)
+		}
+	}
+
+	if a.Distance == 0 || (b.Distance > 0 && b.Distance < a.Distance) {
+		a.Distance = b.Distance
+	}
+	if a.Desc == "" {
+		a.Desc = b.Desc
+	}
+	if a.URL == "" {
+		a.URL = b.URL
+	}
+	if a.ImageURL == "" {
+		a.ImageURL = b.ImageURL
+	}
+	if a.Locale == "" {
+		a.Locale = b.Locale
+	}
+	if len(a.Features) == 0 {
+		a.Features = b.Features
+	}
+	if a.Lat == 0 && a.Lon == 0 {
+		a.Lat, a.Lon = b.Lat, b.Lon
+	}
+	a.Refreshing = a.Refreshing || b.Refreshing
+	return a
+}
+
+// contains returns whether a string is within a slice
+func contains(ss []string, s string) bool {
+	for _, x := range ss {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
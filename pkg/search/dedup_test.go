@@ -0,0 +1,71 @@
+package search
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+)
+
+func TestDedup(t *testing.T) {
+	lake := &campwiz.Campground{ID: "lake", Name: "Lake Campground"}
+	d := time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)
+	avail := func(provider string, url string) []campwiz.Availability {
+		return []campwiz.Availability{{Date: d, SpotCount: 1, URL: url, Provider: provider}}
+	}
+
+	in := []campwiz.Result{
+		{Provider: "ramerica", Providers: []string{"ramerica"}, Name: "LAKE CG", Distance: 50, KnownCampground: lake, MatchScore: "NameMatch", Availability: avail("ramerica", "https://ra/lake")},
+		{Provider: "rcalifornia", Providers: []string{"rcalifornia"}, Name: "Lake Campground", Distance: 49, Desc: "by the lake", KnownCampground: lake, MatchScore: "MangledMatch", Availability: avail("rcalifornia", "https://rc/lake")},
+		{Provider: "ramerica", Providers: []string{"ramerica"}, Name: "Lake Group Camp", Distance: 51, KnownCampground: lake, MatchScore: "NameMatch", Availability: avail("ramerica", "https://ra/group")},
+		{Provider: "rcalifornia", Providers: []string{"rcalifornia"}, Name: "Pine Flat", Lat: 37.5, Lon: -122.1, Availability: avail("rcalifornia", "https://rc/pine")},
+		{Provider: "rcaliforniaAdv", Providers: []string{"rcaliforniaAdv"}, Name: "Pine Flat Camp", Lat: 37.5001, Lon: -122.1001, Refreshing: true, Availability: avail("rcaliforniaAdv", "https://rca/pine")},
+		{Provider: "scc", Providers: []string{"scc"}, Name: "Elsewhere", Lat: 37.2, Lon: -122.1, Availability: avail("scc", "https://scc/elsewhere")},
+		// Different campgrounds within the park, which only matched it by name
+		{Provider: "ramerica", Providers: []string{"ramerica"}, Name: "Lake Park North", Lat: 38.1, Lon: -121.1, KnownCampground: lake, MatchScore: "PropMatch", Availability: avail("ramerica", "https://ra/north")},
+		{Provider: "rcalifornia", Providers: []string{"rcalifornia"}, Name: "Lake Park South", Lat: 38.0, Lon: -121.0, KnownCampground: lake, MatchScore: "PropMatch", Availability: avail("rcalifornia", "https://rc/south")},
+		// The same campground within the park, which is found by its coordinates
+		{Provider: "scc", Providers: []string{"scc"}, Name: "Lake Park South Camp", Lat: 38.0001, Lon: -121.0001, KnownCampground: lake, MatchScore: "MangledPropMatch", Availability: avail("scc", "https://scc/south")},
+	}
+
+	want := []campwiz.Result{
+		{
+			Provider:        "ramerica",
+			Providers:       []string{"ramerica", "rcalifornia"},
+			Name:            "LAKE CG",
+			Distance:        49,
+			Desc:            "by the lake",
+			KnownCampground: lake,
+			MatchScore:      "NameMatch",
+			Availability:    append(avail("ramerica", "https://ra/lake"), avail("rcalifornia", "https://rc/lake")...),
+		},
+		in[2],
+		{
+			Provider:     "rcalifornia",
+			Providers:    []string{"rcalifornia", "rcaliforniaAdv"},
+			Name:         "Pine Flat",
+			Lat:          37.5,
+			Lon:          -122.1,
+			Refreshing:   true,
+			Availability: append(avail("rcalifornia", "https://rc/pine"), avail("rcaliforniaAdv", "https://rca/pine")...),
+		},
+		in[5],
+		in[6],
+		{
+			Provider:        "rcalifornia",
+			Providers:       []string{"rcalifornia", "scc"},
+			Name:            "Lake Park South",
+			Lat:             38.0,
+			Lon:             -121.0,
+			KnownCampground: lake,
+			MatchScore:      "PropMatch",
+			Availability:    append(avail("rcalifornia", "https://rc/south"), avail("scc", "https://scc/south")...),
+		},
+	}
+
+	got := dedup(in)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("dedup() unexpected diff (-want +got):\n%s", diff)
+	}
+}
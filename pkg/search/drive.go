@@ -0,0 +1,77 @@
+package search
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/drive"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+// driveTimes sets the drive time to each result with coordinates from the query location, or
+// from every origin combined like their distances. Drive times are not estimated along a route.
+func driveTimes(q campwiz.Query, rs []campwiz.Result, rt drive.Router) ([]campwiz.Result, error) {
+	if rt == nil || len(q.Route) > 0 {
+		return rs, nil
+	}
+
+	froms := []geo.Point{{Lat: q.Lat, Lon: q.Lon}}
+	if len(q.Origins) > 0 {
+		froms = []geo.Point{}
+		for _, o := range q.Origins {
+			froms = append(froms, geo.Point{Lat: o.Lat, Lon: o.Lon})
+		}
+	}
+
+	located := []int{}
+	to := []geo.Point{}
+	for i, r := range rs {
+		if r.Lat == 0 && r.Lon == 0 {
+			continue
+		}
+		located = append(located, i)
+		to = append(to, geo.Point{Lat: r.Lat, Lon: r.Lon})
+	}
+	if len(to) == 0 {
+		return rs, nil
+	}
+
+	// times are the drive times from each origin to each located result
+	times := [][]time.Duration{}
+	for _, from := range froms {
+		ds, err := rt.Durations(from, to)
+		if err != nil {
+			return rs, fmt.Errorf("%s drive times: %w", rt.Name(), err)
+		}
+		times = append(times, ds)
+	}
+
+	for j, i := range located {
+		var total, longest time.Duration
+		ds := []time.Duration{}
+		for _, ts := range times {
+			d := ts[j]
+			ds = append(ds, d)
+			if d < 0 || total < 0 {
+				total = -1
+			} else {
+				total += d
+			}
+			if d < 0 || longest < 0 {
+				longest = -1
+			} else if d > longest {
+				longest = d
+			}
+		}
+
+		rs[i].DriveTime = longest
+		if q.OriginDistance == campwiz.DistanceSum {
+			rs[i].DriveTime = total
+		}
+		if len(q.Origins) > 0 {
+			rs[i].DriveTimes = ds
+		}
+	}
+	return rs, nil
+}
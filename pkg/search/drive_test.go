@@ -0,0 +1,134 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+// fakeRouter takes an hour per degree of latitude travelled, and can not reach the equator
+type fakeRouter struct {
+	fail bool
+}
+
+func (fakeRouter) Name() string { return "fake" }
+
+func (f fakeRouter) Durations(from geo.Point, to []geo.Point) ([]time.Duration, error) {
+	if f.fail {
+		return nil, fmt.Errorf("unavailable")
+	}
+	ds := []time.Duration{}
+	for _, p := range to {
+		if p.Lat == 0 {
+			ds = append(ds, -1)
+			continue
+		}
+		d := p.Lat - from.Lat
+		if d < 0 {
+			d = -d
+		}
+		ds = append(ds, time.Duration(d*float64(time.Hour)))
+	}
+	return ds, nil
+}
+
+func TestDriveTimes(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "north", Lat: 40, Lon: -120},
+		{Name: "equator", Lat: 0, Lon: -120},
+		{Name: "unknown"},
+	}
+	origins := []campwiz.Origin{{Name: "a", Lat: 37, Lon: -122}, {Name: "b", Lat: 38, Lon: -121}}
+
+	tests := []struct {
+		name    string
+		q       campwiz.Query
+		rt      fakeRouter
+		want    []campwiz.Result
+		wantErr bool
+	}{
+		{
+			name: "location",
+			q:    campwiz.Query{Lat: 37, Lon: -122},
+			want: []campwiz.Result{
+				{Name: "north", Lat: 40, Lon: -120, DriveTime: 3 * time.Hour},
+				{Name: "equator", Lat: 0, Lon: -120, DriveTime: -1},
+				{Name: "unknown"},
+			},
+		},
+		{
+			name: "furthest origin",
+			q:    campwiz.Query{Origins: origins},
+			want: []campwiz.Result{
+				{Name: "north", Lat: 40, Lon: -120, DriveTime: 3 * time.Hour, DriveTimes: []time.Duration{3 * time.Hour, 2 * time.Hour}},
+				{Name: "equator", Lat: 0, Lon: -120, DriveTime: -1, DriveTimes: []time.Duration{-1, -1}},
+				{Name: "unknown"},
+			},
+		},
+		{
+			name: "all origins combined",
+			q:    campwiz.Query{Origins: origins, OriginDistance: campwiz.DistanceSum},
+			want: []campwiz.Result{
+				{Name: "north", Lat: 40, Lon: -120, DriveTime: 5 * time.Hour, DriveTimes: []time.Duration{3 * time.Hour, 2 * time.Hour}},
+				{Name: "equator", Lat: 0, Lon: -120, DriveTime: -1, DriveTimes: []time.Duration{-1, -1}},
+				{Name: "unknown"},
+			},
+		},
+		{
+			name: "along a route",
+			q:    campwiz.Query{Route: toBishop},
+			want: in,
+		},
+		{
+			name:    "router failure",
+			q:       campwiz.Query{Lat: 37, Lon: -122},
+			rt:      fakeRouter{fail: true},
+			want:    in,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := driveTimes(tc.q, append([]campwiz.Result{}, in...), tc.rt)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("driveTimes() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("driveTimes() unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if got, err := driveTimes(campwiz.Query{}, append([]campwiz.Result{}, in...), nil); err != nil || !cmp.Equal(in, got) {
+		t.Errorf("driveTimes() without a router = %+v, %v; want it unchanged", got, err)
+	}
+}
+
+func TestProcessDriveTime(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "north", Lat: 40, Lon: -120},
+		{Name: "near", Lat: 38, Lon: -122},
+		{Name: "equator", Lat: 0, Lon: -120},
+		{Name: "unknown"},
+	}
+	q := campwiz.Query{Lat: 37, Lon: -122, MaxDriveTime: 2 * time.Hour}
+
+	got, errs := process(q, fulltext.Query{}, in, NewIndex(nil, nil), fakeRouter{})
+	if len(errs) > 0 {
+		t.Fatalf("process: %v", errs)
+	}
+	names := []string{}
+	for _, r := range got {
+		names = append(names, r.Name)
+	}
+	// Results without a known drive time are kept, but unreachable ones are not
+	if diff := cmp.Diff([]string{"near", "unknown"}, names); diff != "" {
+		t.Errorf("process() unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -1,49 +1,76 @@
 package search
 
 import (
-	"strings"
-
 	"github.com/tstromberg/campwiz/pkg/campwiz"
-	"k8s.io/klog"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"k8s.io/klog/v2"
 )
 
-// filter applies post-fetch filtering
-func filter(q campwiz.Query, rs []campwiz.Result) []campwiz.Result {
+// filter applies post-fetch filtering, including the full-text keyword query kq
+func filter(q campwiz.Query, rs []campwiz.Result, kq fulltext.Query, idx *Index) []campwiz.Result {
 	fs := []campwiz.Result{}
 
+	// Along a route, the distance of a result is its detour from the route
+	limit := q.MaxDistance
+	if len(q.Route) > 0 {
+		limit = q.MaxDetour
+	}
+
 	for _, r := range rs {
-		if q.MaxDistance > 0 && r.Distance > float64(q.MaxDistance) {
+		if limit > 0 && r.Distance > float64(limit) {
 			klog.V(1).Infof("filtering %q -- too far (%.0f miles)", r.Name, r.Distance)
 			continue
 		}
 
-		if q.MinRating > r.Rating {
+		if q.MaxDriveTime > 0 && len(q.Route) == 0 && (r.DriveTime < 0 || r.DriveTime > q.MaxDriveTime) {
+			klog.V(1).Infof("filtering %q -- too long of a drive (%s)", r.Name, r.DriveTime)
+			continue
+		}
+
+		if q.MinRating > r.Rating || (q.MinRatingExclusive && q.MinRating == r.Rating) {
 			klog.V(1).Infof("filtering %q -- too low of a rating: %.1f", r.Name, r.Rating)
 			continue
 		}
 
-		if len(q.Keywords) > 0 {
-			fields := []string{r.Desc, r.Name}
-			fields = append(fields, r.Features...)
-			if r.KnownCampground != nil {
-				for _, x := range r.KnownCampground.Refs {
-					fields = append(fields, x.Name, x.Locale, x.Desc)
-				}
-			}
-			found := false
-			for _, f := range fields {
-				for _, k := range q.Keywords {
-					if strings.Contains(strings.ToLower(f), strings.ToLower(k)) {
-						found = true
-					}
-				}
-			}
-			if !found {
-				klog.V(1).Infof("filtering %q -- does not match %v", r.Name, q.Keywords)
+		if len(q.SiteKinds) > 0 || len(q.ExcludeKinds) > 0 {
+			avs := wantedKinds(q, r.Availability)
+			// Results which do not describe their availability are kept
+			if len(r.Availability) > 0 && len(avs) == 0 {
+				klog.V(1).Infof("filtering %q -- no wanted kinds of site available", r.Name)
 				continue
 			}
+			r.Availability = avs
+		}
+
+		if !kq.Empty() && !kq.Match(fulltext.NewDocument(append([]string{r.Name, r.Desc, r.Locale}, r.Features...)...), idx.Document(r.KnownCampground)) {
+			klog.V(1).Infof("filtering %q -- does not match %v", r.Name, q.Keywords)
+			continue
 		}
 		fs = append(fs, r)
 	}
 	return fs
 }
+
+// wantedKinds returns the availability of the kinds of site the query wants
+func wantedKinds(q campwiz.Query, avs []campwiz.Availability) []campwiz.Availability {
+	wanted := []campwiz.Availability{}
+	for _, av := range avs {
+		if len(q.SiteKinds) > 0 && !hasKind(q.SiteKinds, av.Kind) {
+			continue
+		}
+		if hasKind(q.ExcludeKinds, av.Kind) {
+			continue
+		}
+		wanted = append(wanted, av)
+	}
+	return wanted
+}
+
+func hasKind(ks []campwiz.SiteKind, k campwiz.SiteKind) bool {
+	for _, x := range ks {
+		if x == k {
+			return true
+		}
+	}
+	return false
+}
@@ -2,62 +2,112 @@ package search
 
 import (
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/metadata"
 )
 
 func TestFilter(t *testing.T) {
-	/* TODO: refactor for new result struct
-	as := []campwiz.AnnotatedResult{
-		{
-			Result: campwiz.Result{
-				Name:     "pretty close",
-				Distance: 30.45,
-			},
-			Refs: []campwiz.Campground{
-				{
-					Source: campwiz.RefSource{
-						RatingDesc: "Scenery",
-					},
-					Rating: 7.0,
-					Desc:   "Tucked into a redwood forest",
-				},
-			},
+	props := map[string]*campwiz.Property{
+		"/ca/close": {
+			ID:   "/ca/close",
+			Name: "Pretty Close State Park",
+			Campgrounds: []*campwiz.Campground{{
+				ID:   "close",
+				Name: "Pretty Close",
+				Refs: map[string]*campwiz.Ref{"cc": {
+					Desc:   metadata.Compress("Tucked into a grove of ancient redwoods, with hiking trails to the lake."),
+					Rating: 7,
+					Lists:  []campwiz.RefList{{Title: "Best Lakeside Campgrounds", Place: 3}},
+				}},
+			}},
 		},
-		{
-			Result: campwiz.Result{
-				Name:     "ugly far",
-				Distance: 90.45,
-			},
-			Refs: []campwiz.Campground{
-				{
-					Source: campwiz.RefSource{
-						RatingDesc: "Scenery",
-					},
-					Rating: 2.0,
-					Desc:   "Hidden in an abandoned dump",
-				},
-			},
+		"/ca/far": {
+			ID:   "/ca/far",
+			Name: "Ugly Far Recreation Area",
+			Campgrounds: []*campwiz.Campground{{
+				ID:   "far",
+				Name: "Ugly Far",
+				Refs: map[string]*campwiz.Ref{"cc": {
+					Desc:   metadata.Compress("Hidden beside an abandoned dump. RV hookups at every site."),
+					Rating: 2,
+				}},
+			}},
 		},
 	}
+	idx := NewIndex(nil, props)
+
+	in := []campwiz.Result{
+		annotate(campwiz.Result{Name: "Pretty Close", Distance: 30.45}, idx),
+		annotate(campwiz.Result{Name: "Ugly Far", Distance: 90.45, Features: []string{"Lake access"}}, idx),
+		{Name: "Unknown", Distance: 10, Desc: "A lakeside meadow"},
+	}
 
-	var tests = []struct {
-		in  campwiz.Query
-		out []string
+	tests := []struct {
+		name string
+		q    campwiz.Query
+		want []string
 	}{
-		{campwiz.Query{MaxDistance: 35.0}, []string{"pretty close"}},
+		{name: "all", q: campwiz.Query{}, want: []string{"Pretty Close", "Ugly Far", "Unknown"}},
+		{name: "distance", q: campwiz.Query{MaxDistance: 35}, want: []string{"Pretty Close", "Unknown"}},
+		{name: "rating", q: campwiz.Query{MinRating: 5}, want: []string{"Pretty Close"}},
+		{name: "rating inclusive", q: campwiz.Query{MinRating: 7}, want: []string{"Pretty Close"}},
+		{name: "rating exclusive", q: campwiz.Query{MinRating: 7, MinRatingExclusive: true}, want: []string{}},
+		{name: "decompressed description", q: campwiz.Query{Keywords: []string{"redwood"}}, want: []string{"Pretty Close"}},
+		{name: "stemmed", q: campwiz.Query{Keywords: []string{"hike"}}, want: []string{"Pretty Close"}},
+		{name: "features and descriptions", q: campwiz.Query{Keywords: []string{"lake"}}, want: []string{"Pretty Close", "Ugly Far"}},
+		{name: "negation", q: campwiz.Query{Keywords: []string{"lake -rv"}}, want: []string{"Pretty Close"}},
+		{name: "award titles", q: campwiz.Query{Keywords: []string{`"best lakeside"`}}, want: []string{"Pretty Close"}},
+		{name: "every keyword", q: campwiz.Query{Keywords: []string{"redwoods", "dump"}}, want: []string{}},
 	}
 
-	for _, tt := range tests {
-		t.Run(tt.in, func(t *testing.T) {
-			got := filter(tt.in)
-			gotNames := []string{}
-			for _, r := range got {
-				gotNames = append(gotNames, got.Result.Name)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kq, err := Keywords(tc.q)
+			if err != nil {
+				t.Fatalf("keywords: %v", err)
+			}
+			got := []string{}
+			for _, r := range filter(tc.q, in, kq, idx) {
+				got = append(got, r.Name)
 			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("filter() unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFilterKinds(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "Tents", Availability: []campwiz.Availability{{Kind: campwiz.Tent, SpotCount: 2}}},
+		{Name: "Mixed", Availability: []campwiz.Availability{{Kind: campwiz.RV, SpotCount: 1}, {Kind: campwiz.Tent, SpotCount: 3}}},
+		{Name: "RVs", Availability: []campwiz.Availability{{Kind: campwiz.RV, SpotCount: 4}, {Kind: campwiz.AccessibleRV, SpotCount: 1}}},
+		{Name: "Undescribed"},
+	}
 
-			if gotNames != tt.out {
-				t.Errorf("got %q, want %q", gotNames, tt.out)
+	tests := []struct {
+		name string
+		q    campwiz.Query
+		want map[string]int
+	}{
+		{name: "any", q: campwiz.Query{}, want: map[string]int{"Tents": 1, "Mixed": 2, "RVs": 2, "Undescribed": 0}},
+		{name: "tent", q: campwiz.Query{SiteKinds: []campwiz.SiteKind{campwiz.Tent}}, want: map[string]int{"Tents": 1, "Mixed": 1, "Undescribed": 0}},
+		{name: "no rv", q: campwiz.Query{ExcludeKinds: []campwiz.SiteKind{campwiz.RV, campwiz.AccessibleRV}}, want: map[string]int{"Tents": 1, "Mixed": 1, "Undescribed": 0}},
+		{name: "rv but not accessible", q: campwiz.Query{SiteKinds: []campwiz.SiteKind{campwiz.RV, campwiz.AccessibleRV}, ExcludeKinds: []campwiz.SiteKind{campwiz.AccessibleRV}}, want: map[string]int{"Mixed": 1, "RVs": 1, "Undescribed": 0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := map[string]int{}
+			for _, r := range filter(tc.q, in, fulltext.Query{}, NewIndex(nil, nil)) {
+				got[r.Name] = len(r.Availability)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("filter() unexpected diff (-want +got):\n%s", diff)
 			}
 		})
 	}
-	*/
 }
@@ -0,0 +1,498 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/agnivade/levenshtein"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"github.com/tstromberg/campwiz/pkg/mangle"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxEdits is the largest edit distance considered an approximate match
+	maxEdits = 2
+
+	// gramPad surrounds names before they are split into trigrams
+	gramPad = "$$"
+)
+
+// Index is a prebuilt index of known campgrounds which results are matched against.
+// Names and their variations are normalized once, and trigram postings narrow each
+// lookup to the properties which could possibly match.
+type Index struct {
+	// srcs are the sources which campground refs are rated by
+	srcs map[string]campwiz.Source
+
+	props []*indexedProperty
+	names []indexedName
+
+	// grams maps trigrams to the offsets of names containing them
+	grams map[string][]int
+	// short are properties with a name of too few trigrams to rule out an approximate match
+	short []int
+	// tiny are properties with a name shorter than a trigram, which may be within any result
+	tiny []int
+
+	// bindings maps provider result IDs to the campgrounds they are bound to
+	bindings map[string]*campwiz.Campground
+
+	// coords are the locations of campgrounds which have one
+	coords map[*campwiz.Campground]point
+
+	// docs are the full-text documents of campgrounds, built upon first use
+	docs     map[*campwiz.Campground]*fulltext.Document
+	docsOnce sync.Once
+
+	// resultNames caches the normalized forms of result names, which recur across searches.
+	// It replaces the process-wide caches that mangle.Normalize and variations once kept.
+	resultNames *nameCache
+}
+
+// normalizedName is a normalized name and its variations
+type normalizedName struct {
+	name       string
+	variations []string
+}
+
+// point is a latitude and longitude
+type point struct {
+	lat float64
+	lon float64
+}
+
+// indexedProperty is a property with precomputed names
+type indexedProperty struct {
+	prop       *campwiz.Property
+	name       string
+	variations []string
+	// last is the campground that property-level matches are attributed to
+	last        *campwiz.Campground
+	campgrounds []indexedCampground
+}
+
+// indexedCampground is a campground with precomputed names
+type indexedCampground struct {
+	cg         *campwiz.Campground
+	name       string
+	variations []string
+}
+
+// indexedName is a property or campground name, or a variation of one
+type indexedName struct {
+	prop int
+	gramCount
+}
+
+// gramCount is the number of distinct trigrams within a name
+type gramCount struct {
+	// padded is the number of trigrams including the padding at either end
+	padded int
+	// inner is the number of trigrams without padding
+	inner int
+}
+
+// short returns whether a name has too few trigrams to rule out an approximate match
+func (g gramCount) short() bool {
+	return g.padded <= 3*maxEdits
+}
+
+// threshold returns how many distinct trigrams two names must share to possibly match.
+//
+// Every match is an equality, a substring, or up to maxEdits edits between two names. A
+// substring shares all of its inner trigrams, and as each edit removes at most 3 trigrams,
+// names within maxEdits share all but 3*maxEdits of the trigrams within either of them.
+func threshold(a, b gramCount) int {
+	t := a.padded
+	if b.padded > t {
+		t = b.padded
+	}
+	t -= 3 * maxEdits
+	if a.inner < t {
+		t = a.inner
+	}
+	if b.inner < t {
+		t = b.inner
+	}
+	return t
+}
+
+// strings returns all of the names a property may be matched by
+func (p *indexedProperty) strings() []string {
+	ss := append([]string{p.name}, p.variations...)
+	for _, c := range p.campgrounds {
+		ss = append(ss, c.name)
+		ss = append(ss, c.variations...)
+	}
+	return ss
+}
+
+// NewIndex builds a match index for a set of properties, rated by srcs
+func NewIndex(srcs map[string]campwiz.Source, props map[string]*campwiz.Property) *Index {
+	ids := []string{}
+	for id := range props {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	idx := &Index{
+		srcs:        srcs,
+		grams:       map[string][]int{},
+		bindings:    map[string]*campwiz.Campground{},
+		coords:      map[*campwiz.Campground]point{},
+		resultNames: newNameCache(maxResultNames),
+	}
+	for i, id := range ids {
+		prop := props[id]
+		propName := mangle.Normalize(prop.Name)
+		p := &indexedProperty{prop: prop, name: propName, variations: variations(propName)}
+		// TODO: A better job guessing which campsite to use
+		for _, c := range prop.Campgrounds {
+			p.last = c
+			knownName := mangle.Normalize(c.Name)
+			p.campgrounds = append(p.campgrounds, indexedCampground{cg: c, name: knownName, variations: variations(knownName)})
+			idx.bind(c)
+			idx.locate(c)
+		}
+		idx.props = append(idx.props, p)
+
+		short, tiny := false, false
+		for _, s := range p.strings() {
+			gs, gc := trigrams(s)
+			short = short || gc.short()
+			tiny = tiny || gc.inner == 0
+
+			n := len(idx.names)
+			idx.names = append(idx.names, indexedName{prop: i, gramCount: gc})
+			for _, g := range gs {
+				idx.grams[g] = append(idx.grams[g], n)
+			}
+		}
+		if short {
+			idx.short = append(idx.short, i)
+		}
+		if tiny {
+			idx.tiny = append(idx.tiny, i)
+		}
+	}
+
+	klog.V(1).Infof("indexed %d properties: %d names, %d trigrams", len(idx.props), len(idx.names), len(idx.grams))
+	return idx
+}
+
+// bindingKey returns the key for an ID within a providers (or reservation hosts) namespace
+func bindingKey(namespace string, id string) string {
+	return namespace + ":" + strings.TrimSpace(id)
+}
+
+// resHost returns the host of a reservation URL, for matching legacy res_id bindings
+func resHost(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// bind records the provider IDs that a campground is bound to
+func (idx *Index) bind(c *campwiz.Campground) {
+	keys := []string{}
+	for provider, ids := range c.ProviderIDs {
+		for _, id := range ids {
+			keys = append(keys, bindingKey(provider, id))
+		}
+	}
+	if c.ResID != "" {
+		if host := resHost(c.ResURL); host != "" {
+			keys = append(keys, bindingKey(host, c.ResID))
+		}
+	}
+
+	for _, k := range keys {
+		if other := idx.bindings[k]; other != nil && other != c {
+			klog.Warningf("%s is bound to both %s/%s and %s/%s, ignoring the latter", k, other.PropertyID, other.ID, c.PropertyID, c.ID)
+			continue
+		}
+		idx.bindings[k] = c
+	}
+}
+
+// locate records the location of a campground, taken from the first of its refs with one
+func (idx *Index) locate(c *campwiz.Campground) {
+	ks := []string{}
+	for k := range c.Refs {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+
+	for _, k := range ks {
+		if ref := c.Refs[k]; ref.Lat != 0 || ref.Lon != 0 {
+			idx.coords[c] = point{lat: ref.Lat, lon: ref.Lon}
+			return
+		}
+	}
+}
+
+// miles returns how far a result is from a campground, or -1 if either location is unknown
+func (idx *Index) miles(r campwiz.Result, c *campwiz.Campground) float64 {
+	p, ok := idx.coords[c]
+	if !ok || (r.Lat == 0 && r.Lon == 0) {
+		return -1
+	}
+	return geo.MilesApart(r.Lat, r.Lon, p.lat, p.lon)
+}
+
+// normalize returns the normalized form of a result name and its variations
+func (idx *Index) normalize(s string) (string, []string) {
+	if n, ok := idx.resultNames.get(s); ok {
+		return n.name, n.variations
+	}
+
+	name := mangle.Normalize(s)
+	n := normalizedName{name: name, variations: variations(name)}
+	idx.resultNames.add(s, n)
+	return n.name, n.variations
+}
+
+// Document returns the full-text document of a known campground: its names, and the
+// names, decompressed descriptions, locales, features and award titles of its refs.
+func (idx *Index) Document(c *campwiz.Campground) *fulltext.Document {
+	idx.docsOnce.Do(func() {
+		idx.docs = map[*campwiz.Campground]*fulltext.Document{}
+		for _, p := range idx.props {
+			for _, ic := range p.campgrounds {
+				fs := []string{p.prop.Name, ic.cg.Name}
+				for _, ref := range ic.cg.Refs {
+					fs = append(fs, ref.Name, refDesc(ref), ref.Locale)
+					fs = append(fs, ref.Features...)
+					for _, l := range ref.Lists {
+						fs = append(fs, l.Title)
+					}
+				}
+				idx.docs[ic.cg] = fulltext.NewDocument(fs...)
+			}
+		}
+		klog.V(1).Infof("built %d full-text documents", len(idx.docs))
+	})
+	return idx.docs[c]
+}
+
+// Bound returns the campground that a result is bound to by its provider ID
+func (idx *Index) Bound(r campwiz.Result) (Match, bool) {
+	if r.ResID == "" {
+		return Match{}, false
+	}
+	if r.Provider != "" {
+		if cg := idx.bindings[bindingKey(r.Provider, r.ResID)]; cg != nil {
+			return Match{SiteID, fmt.Sprintf("%s id %q is bound to %q", r.Provider, strings.TrimSpace(r.ResID), cg.ID), cg}, true
+		}
+	}
+	if host := resHost(r.ResURL); host != "" {
+		if cg := idx.bindings[bindingKey(host, r.ResID)]; cg != nil {
+			return Match{SiteID, fmt.Sprintf("%s res_id %q is bound to %q", host, strings.TrimSpace(r.ResID), cg.ID), cg}, true
+		}
+	}
+	return Match{}, false
+}
+
+// trigrams returns the distinct padded trigrams within a name
+func trigrams(s string) ([]string, gramCount) {
+	rs := []rune(gramPad + s + gramPad)
+	pad := []rune(gramPad)[0]
+	seen := map[string]bool{}
+	gs := []string{}
+	gc := gramCount{}
+	for i := 0; i+3 <= len(rs); i++ {
+		g := string(rs[i : i+3])
+		if seen[g] {
+			continue
+		}
+		seen[g] = true
+		gs = append(gs, g)
+		gc.padded++
+		if rs[i] != pad && rs[i+2] != pad {
+			gc.inner++
+		}
+	}
+	return gs, gc
+}
+
+// candidates returns the offsets of properties which may match any of the given names
+func (idx *Index) candidates(names []string) []int {
+	seen := map[int]bool{}
+	add := func(ps []int) {
+		for _, p := range ps {
+			seen[p] = true
+		}
+	}
+
+	add(idx.tiny)
+	for _, s := range names {
+		gs, gc := trigrams(s)
+		if gc.inner == 0 {
+			return idx.all()
+		}
+		if gc.short() {
+			add(idx.short)
+		}
+
+		shared := map[int]int{}
+		for _, g := range gs {
+			for _, n := range idx.grams[g] {
+				shared[n]++
+			}
+		}
+		for n, c := range shared {
+			in := idx.names[n]
+			if !seen[in.prop] && c >= threshold(gc, in.gramCount) {
+				seen[in.prop] = true
+			}
+		}
+	}
+
+	ps := []int{}
+	for p := range seen {
+		ps = append(ps, p)
+	}
+	sort.Ints(ps)
+	return ps
+}
+
+// all returns the offsets of every property
+func (idx *Index) all() []int {
+	ps := make([]int, len(idx.props))
+	for i := range ps {
+		ps[i] = i
+	}
+	return ps
+}
+
+// Len returns the number of indexed properties
+func (idx *Index) Len() int {
+	return len(idx.props)
+}
+
+// Matches returns all campgrounds which match a result, by ID binding or by name
+func (idx *Index) Matches(r campwiz.Result) []Match {
+	var matches []Match
+	if m, ok := idx.Bound(r); ok {
+		matches = append(matches, m)
+	}
+
+	resName, rvs := idx.normalize(r.Name)
+	ps := idx.candidates(append([]string{resName}, rvs...))
+	klog.V(1).Infof("%q: %d of %d properties are candidates", resName, len(ps), len(idx.props))
+	return append(matches, idx.scan(resName, rvs, ps)...)
+}
+
+// scan compares a normalized result name and its variations against a list of properties
+func (idx *Index) scan(resName string, rvs []string, ps []int) []Match {
+	var matches []Match
+	for _, i := range ps {
+		matches = append(matches, idx.props[i].match(resName, rvs)...)
+	}
+	return matches
+}
+
+// match returns the ways in which a result name matches a property and its campgrounds
+func (p *indexedProperty) match(resName string, rvs []string) []Match {
+	var matches []Match
+	cg := p.last
+
+	if resName == p.name {
+		if len(p.campgrounds) == 1 {
+			matches = append(matches, Match{SinglePropMatch, fmt.Sprintf("result %q = single park %q", resName, p.prop.Name), cg})
+		} else {
+			matches = append(matches, Match{PropMatch, fmt.Sprintf("result %q = multi park %q", resName, p.prop.Name), cg})
+		}
+	}
+
+	for x, kv := range p.variations {
+		if kv == resName {
+			matches = append(matches, Match{MangledPropMatch, fmt.Sprintf("variation %d: %q = %q", x, kv, resName), cg})
+		}
+
+		for i, rv := range rvs {
+			if rv == kv {
+				matches = append(matches, Match{BiMangledPropMatch, fmt.Sprintf("variation %d/%d: %q = %q", i, x, rv, p.name), cg})
+			}
+
+			if strings.Contains(kv, rv) {
+				matches = append(matches, Match{BiMangledPropSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, rv, kv), cg})
+				continue
+			}
+			if strings.Contains(rv, kv) {
+				matches = append(matches, Match{BiMangledPropSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, kv, rv), cg})
+				continue
+			}
+
+			d := levenshtein.ComputeDistance(rv, kv)
+			if d < 3 {
+				matches = append(matches, Match{ApproxPropMatch, fmt.Sprintf("variation %d/%d: %q is %d edits from %q", i, x, rv, d, kv), cg})
+				continue
+			}
+		}
+	}
+
+	for _, c := range p.campgrounds {
+		cg := c.cg
+		knownName := c.name
+
+		if resName == knownName {
+			matches = append(matches, Match{NameMatch, fmt.Sprintf("result %q = known %q", resName, knownName), cg})
+			continue
+		}
+
+		if strings.Contains(resName, knownName) {
+			matches = append(matches, Match{SubMatch, fmt.Sprintf("known %q in result %q", knownName, resName), cg})
+		}
+
+		if strings.Contains(knownName, resName) {
+			matches = append(matches, Match{SubMatch, fmt.Sprintf("result %q in known %q", resName, knownName), cg})
+		}
+
+		for i, rv := range rvs {
+			if rv == knownName {
+				matches = append(matches, Match{MangledMatch, fmt.Sprintf("variation %d: %q = %q", i, rv, knownName), cg})
+				continue
+			}
+
+			if strings.Contains(knownName, rv) {
+				matches = append(matches, Match{MangledSubMatch, fmt.Sprintf("variation %d: result %q in known %q", i, rv, knownName), cg})
+				continue
+			}
+			if strings.Contains(rv, knownName) {
+				matches = append(matches, Match{MangledSubMatch, fmt.Sprintf("variation %d: result %q in known %q", i, knownName, rv), cg})
+				continue
+			}
+
+			for x, kv := range c.variations {
+				if rv == kv {
+					matches = append(matches, Match{BiMangledMatch, fmt.Sprintf("variation %d/%d: %q = %q", i, x, rv, knownName), cg})
+					continue
+				}
+				if strings.Contains(kv, rv) {
+					matches = append(matches, Match{BiMangledSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, rv, kv), cg})
+					continue
+				}
+				if strings.Contains(rv, kv) {
+					matches = append(matches, Match{BiMangledSubMatch, fmt.Sprintf("variation %d/%d: result %q in known %q", i, x, kv, rv), cg})
+					continue
+				}
+
+				d := levenshtein.ComputeDistance(rv, kv)
+				if d < 3 {
+					matches = append(matches, Match{ApproxMatch, fmt.Sprintf("variation %d/%d: %q is %d edits from %q", i, x, rv, d, kv), cg})
+					continue
+				}
+			}
+		}
+	}
+	return matches
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/mangle"
+	"github.com/tstromberg/campwiz/pkg/metadata"
+)
+
+// resultNames returns names resembling what providers return for known campgrounds
+func resultNames(props map[string]*campwiz.Property) []string {
+	names := []string{"Sad River", "Joseph Grant Park", "Colonel Allensworth SHP", "Mount Elky", "A", ""}
+	for _, p := range props {
+		names = append(names, p.Name, p.Name+" Campground")
+		for _, c := range p.Campgrounds {
+			names = append(names, c.Name, "Upper "+c.Name, c.Name+" SP")
+			if len(c.Name) > 4 {
+				names = append(names, c.Name[1:], c.Name[:len(c.Name)-2])
+			}
+		}
+	}
+	return names
+}
+
+func loadIndex(tb testing.TB) (*Index, []string) {
+	tb.Helper()
+	srcs, props, err := metadata.LoadAll()
+	if err != nil {
+		tb.Fatalf("loadall: %v", err)
+	}
+	return NewIndex(srcs, props), resultNames(props)
+}
+
+// TestIndexMatchesScan verifies that narrowing candidates never changes match outcomes
+func TestIndexMatchesScan(t *testing.T) {
+	idx, names := loadIndex(t)
+	if idx.Len() == 0 {
+		t.Fatalf("no properties were indexed")
+	}
+
+	// Full scans are slow, so compare a sample of names
+	for i, name := range names {
+		if i > 10 && i%20 != 0 {
+			continue
+		}
+		got := idx.Matches(campwiz.Result{Name: name})
+
+		resName := mangle.Normalize(name)
+		want := idx.scan(resName, variations(resName), idx.all())
+
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("%q: indexed matches differ from full scan (-want +got):\n%s", name, diff)
+		}
+	}
+}
+
+func TestTrigrams(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  []string
+		count gramCount
+	}{
+		{"", []string{"$$$"}, gramCount{padded: 1}},
+		{"elk", []string{"$$e", "$el", "elk", "lk$", "k$$"}, gramCount{padded: 5, inner: 1}},
+		{"aaaa", []string{"$$a", "$aa", "aaa", "aa$", "a$$"}, gramCount{padded: 5, inner: 1}},
+	}
+	for _, tt := range tests {
+		got, count := trigrams(tt.in)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("trigrams(%q) mismatch (-want +got):\n%s", tt.in, diff)
+		}
+		if count != tt.count {
+			t.Errorf("trigrams(%q) count = %+v, want %+v", tt.in, count, tt.count)
+		}
+	}
+}
+
+func BenchmarkIndexMatches(b *testing.B) {
+	idx, names := loadIndex(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Matches(campwiz.Result{Name: names[i%len(names)]})
+	}
+}
+
+// BenchmarkFullScan compares each result against every property, as annotate once did
+func BenchmarkFullScan(b *testing.B) {
+	idx, names := loadIndex(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resName := mangle.Normalize(names[i%len(names)])
+		idx.scan(resName, variations(resName), idx.all())
+	}
+}
@@ -0,0 +1,68 @@
+package search
+
+import (
+	"container/list"
+	"sync"
+)
+
+// maxResultNames is how many normalized result names an index remembers
+const maxResultNames = 10000
+
+// nameCache is a bounded cache of normalized result names, which forgets the least recently used
+type nameCache struct {
+	mu    sync.Mutex
+	max   int
+	items map[string]*list.Element
+	// recent is ordered from most to least recently used
+	recent *list.List
+}
+
+// nameEntry is a cached result name
+type nameEntry struct {
+	key string
+	n   normalizedName
+}
+
+// newNameCache returns a cache which remembers up to max names
+func newNameCache(max int) *nameCache {
+	return &nameCache{max: max, items: map[string]*list.Element{}, recent: list.New()}
+}
+
+// get returns the normalized form of a result name, if it is cached
+func (c *nameCache) get(key string) (normalizedName, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		return normalizedName{}, false
+	}
+	c.recent.MoveToFront(e)
+	return e.Value.(*nameEntry).n, true
+}
+
+// add caches the normalized form of a result name, forgetting the least recently used if full
+func (c *nameCache) add(key string, n normalizedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		e.Value.(*nameEntry).n = n
+		c.recent.MoveToFront(e)
+		return
+	}
+
+	c.items[key] = c.recent.PushFront(&nameEntry{key: key, n: n})
+	for c.recent.Len() > c.max {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.items, oldest.Value.(*nameEntry).key)
+	}
+}
+
+// len returns how many names are cached
+func (c *nameCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recent.Len()
+}
@@ -0,0 +1,37 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNameCache(t *testing.T) {
+	c := newNameCache(2)
+	c.add("a", normalizedName{name: "a"})
+	c.add("b", normalizedName{name: "b"})
+
+	// Reading "a" makes "b" the least recently used
+	if n, ok := c.get("a"); !ok || n.name != "a" {
+		t.Errorf("get(a) = %+v, %v, want a", n, ok)
+	}
+	c.add("c", normalizedName{name: "c"})
+
+	if _, ok := c.get("b"); ok {
+		t.Errorf("get(b) found, want it forgotten")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.get(k); !ok {
+			t.Errorf("get(%s) not found", k)
+		}
+	}
+}
+
+func TestIndexForgetsResultNames(t *testing.T) {
+	idx := NewIndex(nil, nil)
+	for i := 0; i < maxResultNames+100; i++ {
+		idx.normalize(fmt.Sprintf("Campground %d", i))
+	}
+	if got := idx.resultNames.len(); got != maxResultNames {
+		t.Errorf("index remembers %d result names, want %d", got, maxResultNames)
+	}
+}
@@ -0,0 +1,75 @@
+package search
+
+import (
+	"math"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/geo"
+)
+
+// around returns the query to search providers with. For a group trip, it is centered between
+// the origins, and wide enough to reach every campground within the distance of them all.
+func around(q campwiz.Query) campwiz.Query {
+	if len(q.Origins) == 0 {
+		return q
+	}
+
+	ps := []geo.Point{}
+	for _, o := range q.Origins {
+		ps = append(ps, geo.Point{Lat: o.Lat, Lon: o.Lon})
+	}
+	c := geo.Center(ps...)
+	q.Lat, q.Lon = c.Lat, c.Lon
+
+	if q.MaxDistance == 0 {
+		return q
+	}
+
+	// A campground within reach of every origin is within reach of the furthest from the center,
+	// and one within a total distance of every origin is within that distance of the nearest.
+	spread := 0.0
+	if q.OriginDistance == campwiz.DistanceSum {
+		spread = math.MaxFloat64
+	}
+	for _, p := range ps {
+		d := geo.MilesApart(c.Lat, c.Lon, p.Lat, p.Lon)
+		if q.OriginDistance == campwiz.DistanceSum {
+			spread = math.Min(spread, d)
+		} else {
+			spread = math.Max(spread, d)
+		}
+	}
+	q.MaxDistance += int(math.Ceil(spread))
+	return q
+}
+
+// distances sets the distance of each result from every origin, and combines them into its
+// distance. Results without coordinates keep their distance from the center of the origins.
+func distances(q campwiz.Query, rs []campwiz.Result) []campwiz.Result {
+	if len(q.Origins) == 0 {
+		return rs
+	}
+
+	for i, r := range rs {
+		if r.Lat == 0 && r.Lon == 0 {
+			continue
+		}
+
+		ds := []float64{}
+		total := 0.0
+		furthest := 0.0
+		for _, o := range q.Origins {
+			d := geo.MilesApart(o.Lat, o.Lon, r.Lat, r.Lon)
+			ds = append(ds, d)
+			total += d
+			furthest = math.Max(furthest, d)
+		}
+
+		rs[i].Distances = ds
+		rs[i].Distance = furthest
+		if q.OriginDistance == campwiz.DistanceSum {
+			rs[i].Distance = total
+		}
+	}
+	return rs
+}
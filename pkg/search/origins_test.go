@@ -0,0 +1,131 @@
+package search
+
+import (
+	"math"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+)
+
+var (
+	sanFrancisco = campwiz.Origin{Name: "San Francisco", Lat: 37.7749, Lon: -122.4194}
+	sacramento   = campwiz.Origin{Name: "Sacramento", Lat: 38.5816, Lon: -121.4944}
+)
+
+func TestAround(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       campwiz.Query
+		wantLat float64
+		wantLon float64
+		wantMax int
+	}{
+		{
+			name:    "single location",
+			q:       campwiz.Query{Lat: 37.4, Lon: -122.1, MaxDistance: 100},
+			wantLat: 37.4, wantLon: -122.1, wantMax: 100,
+		},
+		{
+			name:    "furthest origin",
+			q:       campwiz.Query{Origins: []campwiz.Origin{sanFrancisco, sacramento}, MaxDistance: 100},
+			wantLat: 38.18, wantLon: -121.96, wantMax: 138,
+		},
+		{
+			name:    "total distance",
+			q:       campwiz.Query{Origins: []campwiz.Origin{sanFrancisco, sacramento}, MaxDistance: 100, OriginDistance: campwiz.DistanceSum},
+			wantLat: 38.18, wantLon: -121.96, wantMax: 138,
+		},
+		{
+			name:    "total distance is bounded by the nearest origin",
+			q:       campwiz.Query{Origins: []campwiz.Origin{sanFrancisco, sanFrancisco, sacramento}, MaxDistance: 100, OriginDistance: campwiz.DistanceSum},
+			wantLat: 38.05, wantLon: -122.11, wantMax: 126,
+		},
+		{
+			name:    "unlimited",
+			q:       campwiz.Query{Origins: []campwiz.Origin{sanFrancisco, sacramento}},
+			wantLat: 38.18, wantLon: -121.96, wantMax: 0,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := around(tc.q)
+			if math.Abs(got.Lat-tc.wantLat) > 0.01 || math.Abs(got.Lon-tc.wantLon) > 0.01 {
+				t.Errorf("around() centered at %.2f,%.2f, want %.2f,%.2f", got.Lat, got.Lon, tc.wantLat, tc.wantLon)
+			}
+			if got.MaxDistance != tc.wantMax {
+				t.Errorf("around() MaxDistance = %d, want %d", got.MaxDistance, tc.wantMax)
+			}
+		})
+	}
+}
+
+func TestDistances(t *testing.T) {
+	in := []campwiz.Result{
+		// Davis is about 65mi from San Francisco and 14mi from Sacramento
+		{Name: "Davis", Lat: 38.5449, Lon: -121.7405, Distance: 20},
+		// Half Moon Bay is about 22mi from San Francisco and 92mi from Sacramento
+		{Name: "Half Moon Bay", Lat: 37.4636, Lon: -122.4286, Distance: 60},
+		{Name: "Unknown", Distance: 50},
+	}
+	origins := []campwiz.Origin{sanFrancisco, sacramento}
+
+	tests := []struct {
+		name string
+		q    campwiz.Query
+		want []campwiz.Result
+	}{
+		{
+			name: "single location",
+			q:    campwiz.Query{MaxDistance: 100},
+			want: in,
+		},
+		{
+			name: "furthest origin",
+			q:    campwiz.Query{Origins: origins},
+			want: []campwiz.Result{
+				{Name: "Davis", Lat: 38.5449, Lon: -121.7405, Distance: 65, Distances: []float64{65, 14}},
+				{Name: "Half Moon Bay", Lat: 37.4636, Lon: -122.4286, Distance: 92, Distances: []float64{22, 92}},
+				{Name: "Unknown", Distance: 50},
+			},
+		},
+		{
+			name: "total distance",
+			q:    campwiz.Query{Origins: origins, OriginDistance: campwiz.DistanceSum},
+			want: []campwiz.Result{
+				{Name: "Davis", Lat: 38.5449, Lon: -121.7405, Distance: 78, Distances: []float64{65, 14}},
+				{Name: "Half Moon Bay", Lat: 37.4636, Lon: -122.4286, Distance: 114, Distances: []float64{22, 92}},
+				{Name: "Unknown", Distance: 50},
+			},
+		},
+	}
+
+	miles := cmpopts.EquateApprox(0, 1)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := distances(tc.q, append([]campwiz.Result{}, in...))
+			if diff := cmp.Diff(tc.want, got, miles); diff != "" {
+				t.Errorf("distances() unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestProcessOrigins(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "Davis", Lat: 38.5449, Lon: -121.7405},
+		{Name: "Half Moon Bay", Lat: 37.4636, Lon: -122.4286},
+	}
+	q := campwiz.Query{Origins: []campwiz.Origin{sanFrancisco, sacramento}, MaxDistance: 70}
+
+	got, errs := process(q, fulltext.Query{}, in, NewIndex(nil, nil), nil)
+	if len(errs) > 0 {
+		t.Fatalf("process: %v", errs)
+	}
+	if len(got) != 1 || got[0].Name != "Davis" {
+		t.Errorf("process() = %+v, want only Davis within 70mi of both origins", got)
+	}
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"sort"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+)
+
+// halfConfidence is the total source weight at which an aggregate rating is 50% confident
+const halfConfidence = 1.0
+
+// aggregate returns the weighted rating of a campground across sources, how confident
+// that rating is, and how many sources contributed to it. Ratings are normalized to
+// campwiz.RatingScale, and weighted by how much each source is trusted.
+func aggregate(refs map[string]*campwiz.Ref, srcs map[string]campwiz.Source) (float64, float64, int) {
+	keys := []string{}
+	for k, ref := range refs {
+		// Sources which are not trusted at all do not contribute
+		if ref.Rating > 0 && srcs[k].EffectiveWeight() > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return 0, 0, 0
+	}
+
+	// Sorted for a stable floating point sum
+	sort.Strings(keys)
+	total := 0.0
+	weight := 0.0
+	for _, k := range keys {
+		src := srcs[k]
+		w := src.EffectiveWeight()
+		total += src.Normalize(refs[k].Rating) * w
+		weight += w
+	}
+
+	return total / weight, weight / (weight + halfConfidence), len(keys)
+}
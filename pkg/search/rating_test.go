@@ -0,0 +1,79 @@
+package search
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+)
+
+func float(f float64) *float64 { return &f }
+
+func TestAggregate(t *testing.T) {
+	srcs := map[string]campwiz.Source{
+		"guide":     {Name: "Guidebook", RatingMax: 10, Weight: float(3)},
+		"review":    {Name: "Review Site", RatingMax: 5, Trust: float(0.5)},
+		"plain":     {Name: "Plain", RatingMax: 10},
+		"untrusted": {Name: "Untrusted", RatingMax: 10, Trust: float(0)},
+	}
+
+	tests := []struct {
+		name           string
+		refs           map[string]*campwiz.Ref
+		wantRating     float64
+		wantConfidence float64
+		wantSources    int
+	}{
+		{
+			name: "unrated",
+			refs: map[string]*campwiz.Ref{"guide": {Name: "x"}},
+		},
+		{
+			name:           "single",
+			refs:           map[string]*campwiz.Ref{"plain": {Rating: 7}},
+			wantRating:     7,
+			wantConfidence: 0.5,
+			wantSources:    1,
+		},
+		{
+			name:           "guidebook outweighs low trust review",
+			refs:           map[string]*campwiz.Ref{"guide": {Rating: 9}, "review": {Rating: 1}},
+			wantRating:     (9*3 + 2*0.5) / 3.5,
+			wantConfidence: 3.5 / 4.5,
+			wantSources:    2,
+		},
+		{
+			name:           "three sources",
+			refs:           map[string]*campwiz.Ref{"guide": {Rating: 8}, "review": {Rating: 4}, "plain": {Rating: 8}},
+			wantRating:     8,
+			wantConfidence: 4.5 / 5.5,
+			wantSources:    3,
+		},
+		{
+			name:           "untrusted source is ignored",
+			refs:           map[string]*campwiz.Ref{"plain": {Rating: 7}, "untrusted": {Rating: 1}},
+			wantRating:     7,
+			wantConfidence: 0.5,
+			wantSources:    1,
+		},
+		{
+			name: "only untrusted",
+			refs: map[string]*campwiz.Ref{"untrusted": {Rating: 1}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rating, confidence, n := aggregate(tc.refs, srcs)
+			if math.Abs(rating-tc.wantRating) > 0.0001 {
+				t.Errorf("rating = %.4f, want %.4f", rating, tc.wantRating)
+			}
+			if math.Abs(confidence-tc.wantConfidence) > 0.0001 {
+				t.Errorf("confidence = %.4f, want %.4f", confidence, tc.wantConfidence)
+			}
+			if n != tc.wantSources {
+				t.Errorf("sources = %d, want %d", n, tc.wantSources)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package search
+
+import (
+	"math"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// defaultDetour is how far from a route to search if the query does not limit it, in miles
+	defaultDetour = 20.0
+	// maxSamples is how many places along a route providers may be searched around
+	maxSamples = 20
+)
+
+// samples returns the queries to search providers with along a route. Each is centered on a
+// point sampled along the route, and wide enough that together they reach every campground
+// within the detour of the route, including between samples.
+func samples(q campwiz.Query) []campwiz.Query {
+	detour := float64(q.MaxDetour)
+	if detour <= 0 {
+		detour = defaultDetour
+	}
+
+	every := 2 * detour
+	if l := geo.Length(q.Route); l/every > maxSamples-1 {
+		every = l / (maxSamples - 1)
+	}
+	radius := math.Ceil(math.Hypot(detour, every/2))
+
+	qs := []campwiz.Query{}
+	for _, p := range geo.Sample(q.Route, every) {
+		sq := q
+		sq.Lat, sq.Lon = p.Lat, p.Lon
+		sq.MaxDistance = int(radius)
+		qs = append(qs, sq)
+	}
+	return qs
+}
+
+// along searches providers at points sampled along the route of a query. Each result is
+// placed along the route, and is as far from it as the closest sample it was found from.
+func along(providers []string, q campwiz.Query, cs cache.Store) ([]campwiz.Result, []error) {
+	results := []campwiz.Result{}
+	errs := []error{}
+	seen := map[string]int{}
+	failed := map[string]bool{}
+
+	for _, sq := range samples(q) {
+		miles, _ := geo.Project(q.Route, geo.Point{Lat: sq.Lat, Lon: sq.Lon})
+		klog.V(1).Infof("searching %.0fmi along the route, within %d miles of %.4f,%.4f", miles, sq.MaxDistance, sq.Lat, sq.Lon)
+
+		rs, serrs := unfiltered(providers, sq, cs)
+		for _, err := range serrs {
+			// Each sample often fails in the same way
+			if !failed[err.Error()] {
+				failed[err.Error()] = true
+				errs = append(errs, err)
+			}
+		}
+
+		for _, r := range rs {
+			r.RouteMiles = miles
+			key := r.Provider + "/" + r.ResID
+			if r.ResID == "" {
+				key += r.Name
+			}
+
+			i, ok := seen[key]
+			if !ok {
+				seen[key] = len(results)
+				results = append(results, r)
+				continue
+			}
+			if r.Distance < results[i].Distance {
+				results[i] = r
+			}
+		}
+	}
+	return results, errs
+}
+
+// detours sets how far along the route of a query each result is, and how far from it.
+// Results without coordinates keep their distance from the closest sample of the route.
+func detours(q campwiz.Query, rs []campwiz.Result) []campwiz.Result {
+	if len(q.Route) == 0 {
+		return rs
+	}
+
+	for i, r := range rs {
+		if r.Lat == 0 && r.Lon == 0 {
+			continue
+		}
+		rs[i].RouteMiles, rs[i].Distance = geo.Project(q.Route, geo.Point{Lat: r.Lat, Lon: r.Lon})
+	}
+	return rs
+}
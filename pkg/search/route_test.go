@@ -0,0 +1,109 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/geo"
+	"github.com/tstromberg/campwiz/pkg/rank"
+)
+
+// toBishop is the drive from San Francisco over Tioga Pass to Bishop
+var toBishop = []geo.Point{
+	{Lat: 37.7749, Lon: -122.4194},
+	{Lat: 37.9577, Lon: -121.2908},
+	{Lat: 37.8651, Lon: -119.5383},
+	{Lat: 37.9375, Lon: -119.2352},
+	{Lat: 37.3635, Lon: -118.3951},
+}
+
+func TestSamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		q          campwiz.Query
+		wantRadius int
+		wantEvery  float64
+	}{
+		{name: "detour", q: campwiz.Query{Route: toBishop, MaxDetour: 20}, wantRadius: 29, wantEvery: 40},
+		{name: "default detour", q: campwiz.Query{Route: toBishop}, wantRadius: 29, wantEvery: 40},
+		{name: "narrow corridor", q: campwiz.Query{Route: toBishop, MaxDetour: 2}, wantRadius: 7, wantEvery: geo.Length(toBishop) / (maxSamples - 1)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			qs := samples(tc.q)
+			if len(qs) < 2 || len(qs) > maxSamples+1 {
+				t.Fatalf("samples() returned %d queries, want 2 to %d", len(qs), maxSamples+1)
+			}
+
+			first, last := qs[0], qs[len(qs)-1]
+			if (geo.Point{Lat: first.Lat, Lon: first.Lon}) != toBishop[0] || (geo.Point{Lat: last.Lat, Lon: last.Lon}) != toBishop[len(toBishop)-1] {
+				t.Errorf("samples() run from %.4f,%.4f to %.4f,%.4f, want the ends of the route", first.Lat, first.Lon, last.Lat, last.Lon)
+			}
+
+			for i, sq := range qs {
+				if sq.MaxDistance != tc.wantRadius {
+					t.Errorf("sample %d MaxDistance = %d, want %d", i, sq.MaxDistance, tc.wantRadius)
+				}
+				if i == 0 {
+					continue
+				}
+				if d := geo.MilesApart(qs[i-1].Lat, qs[i-1].Lon, sq.Lat, sq.Lon); d > tc.wantEvery*1.01 {
+					t.Errorf("samples %d and %d are %.1f miles apart, want no more than %.1f", i-1, i, d, tc.wantEvery)
+				}
+			}
+		})
+	}
+}
+
+func TestDetours(t *testing.T) {
+	in := []campwiz.Result{
+		// Tuolumne Meadows is beside the road, about 169 miles along
+		{Name: "Tuolumne Meadows", Lat: 37.8735, Lon: -119.3497, Distance: 40},
+		// Big Sur is far from the road, closest to its start
+		{Name: "Big Sur", Lat: 36.2704, Lon: -121.8081, Distance: 15},
+		{Name: "Unknown", Distance: 12, RouteMiles: 80},
+	}
+
+	want := []campwiz.Result{
+		{Name: "Tuolumne Meadows", Lat: 37.8735, Lon: -119.3497, Distance: 2, RouteMiles: 169},
+		{Name: "Big Sur", Lat: 36.2704, Lon: -121.8081, Distance: 109, RouteMiles: 12},
+		{Name: "Unknown", Distance: 12, RouteMiles: 80},
+	}
+
+	got := detours(campwiz.Query{Route: toBishop}, append([]campwiz.Result{}, in...))
+	if diff := cmp.Diff(want, got, cmpopts.EquateApprox(0, 1)); diff != "" {
+		t.Errorf("detours() unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if got := detours(campwiz.Query{}, append([]campwiz.Result{}, in...)); !cmp.Equal(in, got) {
+		t.Errorf("detours() without a route = %+v, want it unchanged", got)
+	}
+}
+
+func TestProcessRoute(t *testing.T) {
+	in := []campwiz.Result{
+		{Name: "Tuolumne Meadows", Lat: 37.8735, Lon: -119.3497},
+		{Name: "Big Sur", Lat: 36.2704, Lon: -121.8081},
+		{Name: "Mono Lake", Lat: 37.9988, Lon: -119.1298},
+		{Name: "Mount Diablo", Lat: 37.8816, Lon: -121.9142},
+	}
+	// MaxDistance is ignored along a route
+	q := campwiz.Query{Route: toBishop, MaxDetour: 15, MaxDistance: 1, Sort: rank.Route}
+
+	got, errs := process(q, fulltext.Query{}, in, NewIndex(nil, nil), nil)
+	if len(errs) > 0 {
+		t.Fatalf("process: %v", errs)
+	}
+	names := []string{}
+	for _, r := range got {
+		names = append(names, r.Name)
+	}
+	want := []string{"Mount Diablo", "Tuolumne Meadows", "Mono Lake"}
+	if diff := cmp.Diff(want, names); diff != "" {
+		t.Errorf("process() unexpected diff (-want +got):\n%s", diff)
+	}
+}
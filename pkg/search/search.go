@@ -2,31 +2,83 @@ package search
 
 import (
 	"fmt"
-	"sort"
+	"strings"
 
 	"github.com/tstromberg/campwiz/pkg/backend"
 	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
-	"k8s.io/klog"
+	"github.com/tstromberg/campwiz/pkg/drive"
+	"github.com/tstromberg/campwiz/pkg/fulltext"
+	"github.com/tstromberg/campwiz/pkg/rank"
+	"k8s.io/klog/v2"
 )
 
 var DefaultProviders = []string{"ramerica", "rcalifornia", "scc", "smc"}
 
-// Run is a one-stop query shop: talks to backends, annotates, provides filtering
-func Run(providers []string, q campwiz.Query, cs cache.Store, props map[string]*campwiz.Property) ([]campwiz.Result, []error) {
-	rs, errs := unfiltered(providers, q, cs)
-
-	as := []campwiz.Result{}
-	for _, r := range rs {
-		as = append(as, annotate(r, props))
+// Run is a one-stop query shop: talks to backends, annotates, provides filtering.
+// Drive times are estimated if a router is given. It is safe to call concurrently,
+// including with the same index, cache store and router.
+func Run(providers []string, q campwiz.Query, cs cache.Store, idx *Index, rt drive.Router) ([]campwiz.Result, []error) {
+	kq, err := Keywords(q)
+	if err != nil {
+		return nil, []error{err}
 	}
 
-	fs := filter(q, as)
+	rs, errs := fetch(providers, q, cs)
+	fs, perrs := process(q, kq, rs, idx, rt)
+	return fs, append(errs, perrs...)
+}
 
-	sort.Slice(fs, func(i, j int) bool { return fs[i].Rating > fs[j].Rating })
+// process annotates, merges, measures, filters and ranks the results of a query
+func process(q campwiz.Query, kq fulltext.Query, rs []campwiz.Result, idx *Index, rt drive.Router) ([]campwiz.Result, []error) {
+	errs := []error{}
+	ms, err := driveTimes(q, detours(q, distances(q, dedup(annotateAll(rs, idx)))), rt)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	fs := filter(q, ms, kq, idx)
+
+	s, err := rank.New(q.Sort, q.SortWeights)
+	if err != nil {
+		s, _ = rank.New(rank.Default, nil)
+		errs = append(errs, fmt.Errorf("rank: %w", err))
+	}
+	rank.Sort(fs, s)
 	return fs, errs
 }
 
+// Keywords parses the full-text query within the keywords of a query
+func Keywords(q campwiz.Query) (fulltext.Query, error) {
+	kq, err := fulltext.Parse(strings.Join(q.Keywords, " "))
+	if err != nil {
+		return kq, fmt.Errorf("keywords: %w", err)
+	}
+	return kq, nil
+}
+
+// Annotated returns results across providers, matched to known campgrounds but without filters
+func Annotated(providers []string, q campwiz.Query, cs cache.Store, idx *Index) ([]campwiz.Result, []error) {
+	rs, errs := fetch(providers, q, cs)
+	return annotateAll(rs, idx), errs
+}
+
+// fetch searches providers along the route of a query, or else around its origins or location
+func fetch(providers []string, q campwiz.Query, cs cache.Store) ([]campwiz.Result, []error) {
+	if len(q.Route) > 0 {
+		return along(providers, q, cs)
+	}
+	return unfiltered(providers, around(q), cs)
+}
+
+// annotateAll matches each result to a known campground
+func annotateAll(rs []campwiz.Result, idx *Index) []campwiz.Result {
+	as := []campwiz.Result{}
+	for _, r := range rs {
+		as = append(as, annotate(r, idx))
+	}
+	return as
+}
+
 // unfiltered searches for results across providers, without filters
 func unfiltered(providers []string, q campwiz.Query, cs cache.Store) ([]campwiz.Result, []error) {
 	klog.V(1).Infof("search campwiz.Query: %+v", q)
@@ -38,16 +90,23 @@ func unfiltered(providers []string, q campwiz.Query, cs cache.Store) ([]campwiz.
 	for _, pname := range providers {
 		p, err := backend.New(backend.Config{Type: pname, Store: cs})
 		if err != nil {
-			errs = append(errs, fmt.Errorf("%s init: %v", pname, err))
+			errs = append(errs, fmt.Errorf("%s init: %w", pname, err))
 			continue
 		}
 
 		prs, err := p.List(q)
 		if err != nil {
-			errs = append(errs, fmt.Errorf("%s list: %v", pname, err))
+			errs = append(errs, fmt.Errorf("%s list: %w", pname, err))
 			continue
 		}
 
+		for i := range prs {
+			prs[i].Provider = pname
+			prs[i].Providers = []string{pname}
+			for j := range prs[i].Availability {
+				prs[i].Availability[j].Provider = pname
+			}
+		}
 		results = append(results, prs...)
 	}
 
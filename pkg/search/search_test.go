@@ -0,0 +1,154 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/drive"
+)
+
+// TestConcurrentProcess verifies that searches sharing an index do not race or interfere
+func TestConcurrentProcess(t *testing.T) {
+	idx, names := loadIndex(t)
+	if len(names) > 50 {
+		names = names[:50]
+	}
+
+	rs := []campwiz.Result{}
+	for i, n := range names {
+		rs = append(rs, campwiz.Result{Name: n, Distance: float64(i), Provider: "fake", ResID: fmt.Sprintf("%d", i)})
+	}
+
+	q := campwiz.Query{Keywords: []string{"-zzyzx"}}
+	kq, err := Keywords(q)
+	if err != nil {
+		t.Fatalf("keywords: %v", err)
+	}
+
+	const workers = 16
+	got := make([][]campwiz.Result, workers)
+	errs := make([][]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			in := append([]campwiz.Result{}, rs...)
+			got[i], errs[i] = process(q, kq, in, idx, drive.Heuristic{})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, es := range errs {
+		if len(es) > 0 {
+			t.Fatalf("process #%d: %v", i, es)
+		}
+	}
+
+	for i := 1; i < workers; i++ {
+		if diff := cmp.Diff(got[0], got[i]); diff != "" {
+			t.Errorf("process() #%d differs from #0 (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+// fakeUpstream serves the San Mateo County availability feed for every request
+type fakeUpstream struct {
+	feed []byte
+	mu   sync.Mutex
+	hits int
+}
+
+func (f *fakeUpstream) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.hits++
+	f.mu.Unlock()
+
+	body := []byte("<html></html>")
+	if strings.HasSuffix(r.URL.Path, "/feed.html") {
+		body = f.feed
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       ioutil.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+// TestConcurrentRun verifies that searches sharing a cache store and index, as the server's do,
+// do not race while fetching, and share what each other fetched
+func TestConcurrentRun(t *testing.T) {
+	feed, err := ioutil.ReadFile("../backend/testdata/smc_feed.xml")
+	if err != nil {
+		t.Fatalf("readfile: %v", err)
+	}
+	up := &fakeUpstream{feed: feed}
+	orig := http.DefaultTransport
+	http.DefaultTransport = up
+	t.Cleanup(func() { http.DefaultTransport = orig })
+
+	cs := cache.NewMemory(cache.MemoryConfig{})
+	idx, _ := loadIndex(t)
+	q := campwiz.Query{Lat: 37.4, Lon: -122.1, MaxDistance: 100, StayLength: 2, Dates: []time.Time{time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)}}
+
+	const workers = 8
+	got := make([][]campwiz.Result, workers)
+	errs := make([][]error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = Run([]string{"smc"}, q, cs, idx, drive.Heuristic{})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, es := range errs {
+		if len(es) > 0 {
+			t.Fatalf("Run #%d: %v", i, es)
+		}
+	}
+	if len(got[0]) == 0 {
+		t.Fatalf("Run() returned no results")
+	}
+	for i := 1; i < workers; i++ {
+		if diff := cmp.Diff(got[0], got[i]); diff != "" {
+			t.Errorf("Run() #%d differs from #0 (-want +got):\n%s", i, diff)
+		}
+	}
+
+	// Every page is now cached, so another search should not reach the upstream
+	before := up.hits
+	if _, errs := Run([]string{"smc"}, q, cs, idx, drive.Heuristic{}); len(errs) > 0 {
+		t.Fatalf("Run: %v", errs)
+	}
+	if up.hits != before {
+		t.Errorf("got %d upstream hits for a cached search, want 0", up.hits-before)
+	}
+}
+
+// TestRunNotCached verifies that cache-only misses can be told apart from other failures
+func TestRunNotCached(t *testing.T) {
+	q := campwiz.Query{Lat: 37.4, Lon: -122.1, Dates: []time.Time{time.Date(2021, 3, 5, 0, 0, 0, 0, time.UTC)}, StayLength: 2, Policy: campwiz.FetchCacheOnly}
+	_, errs := Run(DefaultProviders, q, cache.NewMemory(cache.MemoryConfig{}), NewIndex(nil, nil), nil)
+	if len(errs) == 0 {
+		t.Fatalf("Run() returned no errors, want one per provider")
+	}
+	for _, err := range errs {
+		if !errors.Is(err, cache.ErrNotCached) {
+			t.Errorf("Run() error = %v, want it to wrap cache.ErrNotCached", err)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package site
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"k8s.io/klog/v2"
+)
+
+type cacheContext struct {
+	Filter  cache.Filter
+	Entries []cache.Entry
+	Summary cache.Summary
+	Entry   *cache.Entry
+	Purged  int
+	Memory  *cache.Stats
+	Metrics cache.EntryMetrics
+	Version string
+	// CSRF is the token which purges must be submitted with
+	CSRF string
+}
+
+// cacheFilter returns a cache filter from URL parameters
+func cacheFilter(u *url.URL) cache.Filter {
+	return cache.Filter{
+		Host:       getStr(u, "host", ""),
+		Provider:   getStr(u, "provider", ""),
+		StatusCode: getInt(u, "status", 0),
+		OlderThan:  getDuration(u, "older_than", 0),
+		NewerThan:  getDuration(u, "newer_than", 0),
+		Failing:    getStr(u, "failing", "") != "",
+	}
+}
+
+// Cache returns the cache administration page
+func (h *Handlers) Cache() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		l, ok := h.c.Cache.(cache.Lister)
+		if !ok {
+			h.error(w, fmt.Errorf("cache store %T can not be listed", h.c.Cache))
+			return
+		}
+
+		// Purges are POSTed, so read parameters from the form rather than the URL
+		if err := r.ParseForm(); err != nil {
+			h.error(w, err)
+			return
+		}
+		u := &url.URL{RawQuery: r.Form.Encode()}
+
+		ctx := cacheContext{
+			Filter:  cacheFilter(u),
+			Metrics: cache.Metrics(h.c.Cache),
+			Version: VERSION,
+			CSRF:    h.csrfToken,
+		}
+
+		if ms, ok := h.c.Cache.(*cache.MemoryStore); ok {
+			st := ms.Stats()
+			ctx.Memory = &st
+		}
+
+		if key := getStr(u, "key", ""); key != "" {
+			e := cache.Inspect(h.c.Cache, key)
+			ctx.Entry = &e
+		}
+
+		if r.Method == http.MethodPost {
+			// Other sites may submit forms here, but can not read the token to submit with them
+			if subtle.ConstantTimeCompare([]byte(r.PostForm.Get("csrf")), []byte(h.csrfToken)) != 1 {
+				http.Error(w, "missing or invalid csrf token", http.StatusForbidden)
+				return
+			}
+			if ctx.Filter == (cache.Filter{}) {
+				h.error(w, fmt.Errorf("refusing to purge every entry"))
+				return
+			}
+			purged, err := cache.Purge(l, ctx.Filter)
+			if err != nil {
+				h.error(w, err)
+				return
+			}
+			klog.Infof("purged %d cache entries matching %+v", len(purged), ctx.Filter)
+			ctx.Purged = len(purged)
+		}
+
+		es, err := cache.List(l, ctx.Filter)
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+		ctx.Entries = es
+		ctx.Summary = cache.Summarize(es)
+
+		p := filepath.Join(h.c.BaseDirectory, "cache.tmpl")
+		outTmpl, err := ioutil.ReadFile(p)
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+
+		fmap := template.FuncMap{
+			"age": func(d time.Duration) string { return d.Round(time.Second).String() },
+		}
+
+		tmpl := template.Must(template.New("cache").Funcs(fmap).Parse(string(outTmpl)))
+		if err := tmpl.ExecuteTemplate(w, "cache", ctx); err != nil {
+			h.error(w, err)
+			return
+		}
+	}
+}
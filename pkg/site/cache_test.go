@@ -0,0 +1,69 @@
+package site
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+)
+
+func TestCacheEscapes(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Evil", "<script>header()</script>")
+		w.Write([]byte("<script>body()</script>"))
+	}))
+	defer upstream.Close()
+
+	cs := cache.NewMemory(cache.MemoryConfig{})
+	req := cache.Request{Provider: "evil", Method: "GET", URL: upstream.URL + "/?<script>url()</script>"}
+	if _, err := cache.Fetch(req, cs); err != nil {
+		t.Fatalf("fetch: %v", err)
+	}
+
+	h := New(&Config{BaseDirectory: "../../site", Cache: cs})
+	u := "/cache?" + url.Values{"key": {req.Key()}, "host": {`"><script>host()</script>`}}.Encode()
+	w := httptest.NewRecorder()
+	h.Cache()(w, httptest.NewRequest("GET", u, nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s = %d: %s", u, w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "<script>") {
+		t.Errorf("GET %s returned an unescaped script:\n%s", u, w.Body)
+	}
+}
+
+func TestCachePurgeCSRF(t *testing.T) {
+	cs := cache.NewMemory(cache.MemoryConfig{})
+	h := New(&Config{BaseDirectory: "../../site", Cache: cs})
+
+	tests := []struct {
+		name string
+		form url.Values
+		want int
+	}{
+		{name: "no token", form: url.Values{"failing": {"1"}}, want: http.StatusForbidden},
+		{name: "wrong token", form: url.Values{"failing": {"1"}, "csrf": {"guess"}}, want: http.StatusForbidden},
+		{name: "token in the URL", form: url.Values{"failing": {"1"}}, want: http.StatusForbidden},
+		{name: "token", form: url.Values{"failing": {"1"}, "csrf": {h.csrfToken}}, want: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			target := "/cache"
+			if tc.name == "token in the URL" {
+				target += "?csrf=" + h.csrfToken
+			}
+			r := httptest.NewRequest("POST", target, strings.NewReader(tc.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			h.Cache()(w, r)
+			if w.Code != tc.want {
+				t.Errorf("POST %v = %d, want %d: %s", tc.form, w.Code, tc.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package site
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"k8s.io/klog/v2"
+)
+
+// requestIDRe matches request ids which are safe to use as file names
+var requestIDRe = regexp.MustCompile(`^[\w-]{1,64}$`)
+
+// requestID returns a new id for an incoming request. It is always chosen here, with a random
+// suffix, so that clients can neither overwrite nor guess the recordings of other requests.
+func requestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		klog.Warningf("unable to read random bytes: %v", err)
+	}
+	return fmt.Sprintf("%s-%x", time.Now().Format("20060102-150405"), b)
+}
+
+// harPath returns the path to the HAR file for a request id
+func (h *Handlers) harPath(id string) string {
+	return filepath.Join(h.c.HARDirectory, id+".har")
+}
+
+// recorder returns a store which records upstream traffic if requested with ?har=1
+func (h *Handlers) recorder(r *http.Request) (cache.Store, *cache.Recorder) {
+	if h.c.HARDirectory == "" || getStr(r.URL, "har", "") == "" {
+		return h.c.Cache, nil
+	}
+	rec := cache.NewRecorder()
+	return cache.Record(h.c.Cache, rec), rec
+}
+
+// HAR returns a previously recorded HAR file by request id
+func (h *Handlers) HAR() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if h.c.HARDirectory == "" || !h.c.Admin {
+			http.Error(w, "HAR recording is disabled", http.StatusNotFound)
+			return
+		}
+		id := getStr(r.URL, "id", "")
+		if !requestIDRe.MatchString(id) {
+			http.Error(w, fmt.Sprintf("invalid request id: %q", id), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+".har"))
+		http.ServeFile(w, r, h.harPath(id))
+	}
+}
@@ -0,0 +1,50 @@
+package site
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		id := requestID()
+		if !requestIDRe.MatchString(id) {
+			t.Errorf("requestID() = %q, which is not a safe file name", id)
+		}
+		if seen[id] {
+			t.Errorf("requestID() = %q, which was already returned", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestHARRequiresAdmin(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "recorded.har"), []byte(`{"log":{}}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		admin bool
+		want  int
+	}{
+		{name: "public", admin: false, want: http.StatusNotFound},
+		{name: "admin", admin: true, want: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := New(&Config{HARDirectory: dir, Admin: tc.admin})
+			w := httptest.NewRecorder()
+			h.HAR()(w, httptest.NewRequest("GET", "/har?id=recorded", nil))
+			if w.Code != tc.want {
+				t.Errorf("GET /har = %d, want %d", w.Code, tc.want)
+			}
+		})
+	}
+}
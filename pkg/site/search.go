@@ -1,16 +1,22 @@
 package site
 
 import (
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/url"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/drive"
+	"github.com/tstromberg/campwiz/pkg/geo"
 	"github.com/tstromberg/campwiz/pkg/mangle"
+	"github.com/tstromberg/campwiz/pkg/parse"
+	"github.com/tstromberg/campwiz/pkg/rank"
 	"github.com/tstromberg/campwiz/pkg/search"
 	"k8s.io/klog/v2"
 )
@@ -24,6 +30,16 @@ type templateContext struct {
 	Today      time.Time
 	SelectDate time.Time
 	Version    string
+	// HAR is the request id that upstream traffic was recorded under, if it may be served
+	HAR string
+	// Sorts are the names of the ranking strategies results may be ordered by
+	Sorts []string
+	// DriveHours are the drive times results may be limited to, in hours
+	DriveHours []float64
+	// Debug shows why each result was matched to a known campground
+	Debug bool
+	// Text is the description of the trip which was searched for, if any
+	Text string
 }
 
 func futureFriday() time.Time {
@@ -37,12 +53,69 @@ func (h *Handlers) Search() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		klog.Infof("Incoming request: %+v", r)
 		q := campwiz.Query{
-			Lon:         h.c.Longitude,
-			Lat:         h.c.Latitude,
-			StayLength:  getInt(r.URL, "nights", 2),
-			MaxDistance: getInt(r.URL, "distance", 100),
-			MinRating:   getFloat(r.URL, "min_rating", 0.0),
-			Keywords:    []string{getStr(r.URL, "keywords", "")},
+			Lon:          h.c.Longitude,
+			Lat:          h.c.Latitude,
+			StayLength:   getInt(r.URL, "nights", 2),
+			MaxDistance:  getInt(r.URL, "distance", 100),
+			MaxDriveTime: getDuration(r.URL, "drive_time", 0),
+			MinRating:    getFloat(r.URL, "min_rating", 0.0),
+			Keywords:     []string{getStr(r.URL, "keywords", "")},
+			// Rather than making visitors wait, refresh expired pages in the background
+			ServeStale: true,
+		}
+
+		policy, err := campwiz.ParseFetchPolicy(getStr(r.URL, "policy", ""))
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+		// Visitors may not force every provider to be fetched again
+		if policy != campwiz.FetchNormal && policy != campwiz.FetchCacheOnly {
+			http.Error(w, fmt.Sprintf("fetch policy %q is not available", policy), http.StatusBadRequest)
+			return
+		}
+		q.Policy = policy
+
+		q.Sort = getStr(r.URL, "sort", rank.Default)
+		ws, err := rank.ParseWeights(getStr(r.URL, "weights", ""))
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+		q.SortWeights = ws
+
+		if _, err := search.Keywords(q); err != nil {
+			h.error(w, err)
+			return
+		}
+
+		for _, s := range r.URL.Query()["origin"] {
+			if strings.TrimSpace(s) == "" {
+				continue
+			}
+			o, err := campwiz.ParseOrigin(s)
+			if err != nil {
+				h.error(w, err)
+				return
+			}
+			q.Origins = append(q.Origins, o)
+		}
+		q.OriginDistance, err = campwiz.ParseOriginDistance(getStr(r.URL, "origin_distance", ""))
+		if err != nil {
+			h.error(w, err)
+			return
+		}
+
+		if rt := strings.TrimSpace(getStr(r.URL, "route", "")); rt != "" {
+			q.Route, err = geo.ParsePath(rt)
+			if err != nil {
+				h.error(w, err)
+				return
+			}
+			q.MaxDetour = getInt(r.URL, "detour", 20)
+			if getStr(r.URL, "sort", "") == "" {
+				q.Sort = rank.Route
+			}
 		}
 
 		selectDate := futureFriday()
@@ -59,12 +132,40 @@ func (h *Handlers) Search() http.HandlerFunc {
 
 		var rs []campwiz.Result
 		var errs []error
+		var harID string
+
+		// A description of the trip, such as "tent near Big Sur next weekend", is searched on
+		// the form date unless it names its own dates. Mistakes are shown alongside the form.
+		text := strings.TrimSpace(getStr(r.URL, "q", ""))
+		if text != "" {
+			pq, err := parse.Query(text, q, time.Now())
+			if err != nil {
+				errs = append(errs, err)
+				q.Dates = nil
+			} else {
+				q = pq
+				if len(q.Dates) == 0 {
+					q.Dates = []time.Time{selectDate}
+				}
+				selectDate = q.Dates[0]
+			}
+		}
 
 		if len(q.Dates) > 0 {
-			rs, errs = search.Run(h.c.Providers, q, h.c.Cache, h.c.Properties)
+			cs, rec := h.recorder(r)
+			rs, errs = search.Run(h.c.Providers, q, cs, h.c.Index, h.c.Router)
 			if len(errs) > 0 {
 				klog.Errorf("search errors: %v", errs)
 			}
+			if rec != nil {
+				harID = requestID()
+				if err := rec.WriteFile(h.harPath(harID)); err != nil {
+					h.error(w, err)
+					return
+				}
+				klog.Infof("recorded %d requests for %s", rec.Len(), harID)
+				w.Header().Set("X-Request-Id", harID)
+			}
 		}
 
 		p := filepath.Join(h.c.BaseDirectory, "search.tmpl")
@@ -77,17 +178,27 @@ func (h *Handlers) Search() http.HandlerFunc {
 		fmap := template.FuncMap{
 			"Ellipsis": ellipse,
 			"toDate":   toDate,
+			"drive":    drive.Summary,
+			"join":     strings.Join,
+			"percent":  func(f float64) string { return fmt.Sprintf("%.0f%%", f*100) },
 		}
 
 		tmpl := template.Must(template.New("http").Funcs(fmap).Parse(string(outTmpl)))
 		ctx := templateContext{
 			Query:      q,
 			Sources:    h.c.Sources,
+			Sorts:      rank.Names(),
+			DriveHours: []float64{1, 2, 3, 4},
 			Results:    rs,
 			Errors:     errs,
 			SelectDate: selectDate,
 			Today:      time.Now(),
 			Version:    VERSION,
+			Debug:      getStr(r.URL, "debug", "") != "",
+			Text:       text,
+		}
+		if h.c.Admin {
+			ctx.HAR = harID
 		}
 		err = tmpl.ExecuteTemplate(w, "http", ctx)
 		if err != nil {
@@ -133,6 +244,20 @@ func getFloat(url *url.URL, key string, fallback float64) float64 {
 	return fallback
 }
 
+// helper to get durations from a URL
+func getDuration(url *url.URL, key string, fallback time.Duration) time.Duration {
+	vals := url.Query()[key]
+	if len(vals) == 1 && vals[0] != "" {
+		d, err := time.ParseDuration(vals[0])
+		if err != nil {
+			klog.Warningf("bad %s duration value: %v", key, vals)
+			return fallback
+		}
+		return d
+	}
+	return fallback
+}
+
 // helper to get string from a URL
 func getStr(url *url.URL, key string, fallback string) string {
 	vals := url.Query()[key]
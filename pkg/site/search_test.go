@@ -0,0 +1,46 @@
+package site
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tstromberg/campwiz/pkg/cache"
+	"github.com/tstromberg/campwiz/pkg/search"
+)
+
+func TestSearchPolicy(t *testing.T) {
+	h := New(&Config{BaseDirectory: "../../site", Cache: cache.NewMemory(cache.MemoryConfig{}), Index: search.NewIndex(nil, nil)})
+
+	tests := []struct {
+		policy string
+		want   int
+	}{
+		{policy: "", want: http.StatusOK},
+		{policy: "normal", want: http.StatusOK},
+		{policy: "cache-only", want: http.StatusOK},
+		{policy: "refresh", want: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.policy, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.Search()(w, httptest.NewRequest("GET", "/search?dates=2021-03-05&policy="+tc.policy, nil))
+			if w.Code != tc.want {
+				t.Errorf("GET /search with policy %q = %d, want %d", tc.policy, w.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestSearchKeywordsRoundTrip(t *testing.T) {
+	h := New(&Config{BaseDirectory: "../../site", Cache: cache.NewMemory(cache.MemoryConfig{}), Index: search.NewIndex(nil, nil)})
+	w := httptest.NewRecorder()
+	h.Search()(w, httptest.NewRequest("GET", "/search?q=lake+beach+2021-03-05", nil))
+
+	want := `name="keywords" placeholder="lake -rv" value="lake beach"`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("GET /search does not contain %s:\n%s", want, w.Body)
+	}
+}
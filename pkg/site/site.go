@@ -2,6 +2,7 @@
 package site
 
 import (
+	"crypto/rand"
 	"fmt"
 	"net/http"
 	"runtime"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/tstromberg/campwiz/pkg/cache"
 	"github.com/tstromberg/campwiz/pkg/campwiz"
+	"github.com/tstromberg/campwiz/pkg/drive"
+	"github.com/tstromberg/campwiz/pkg/search"
 	"k8s.io/klog/v2"
 )
 
@@ -21,7 +24,14 @@ type Config struct {
 	Cache         cache.Store
 	Sources       map[string]campwiz.Source
 	Properties    map[string]*campwiz.Property
+	Index         *search.Index
 	Providers     []string
+	// HARDirectory is where to record upstream traffic for searches requested with ?har=1 (disabled if empty)
+	HARDirectory string
+	// Router estimates drive times to results (disabled if nil)
+	Router drive.Router
+	// Admin serves recorded upstream traffic, which may include cookies, from /har
+	Admin bool
 
 	// For hardcoding a site to a particular address
 	Latitude  float64
@@ -29,9 +39,14 @@ type Config struct {
 }
 
 func New(c *Config) *Handlers {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		klog.Exitf("unable to read random bytes: %v", err)
+	}
 	return &Handlers{
 		c:         *c,
 		startTime: time.Now(),
+		csrfToken: fmt.Sprintf("%x", b),
 	}
 }
 
@@ -40,6 +55,8 @@ type Handlers struct {
 	c Config
 
 	startTime time.Time
+	// csrfToken must be submitted with forms which change state
+	csrfToken string
 }
 
 // Root redirects to leaderboard.
@@ -54,6 +71,10 @@ func (h *Handlers) Healthz() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(fmt.Sprintf("ok: started at %s", h.startTime)))
+		if ms, ok := h.c.Cache.(*cache.MemoryStore); ok {
+			w.Write([]byte(fmt.Sprintf("\ncache: %s", ms.Stats())))
+		}
+		w.Write([]byte(fmt.Sprintf("\nentries: %s", cache.Metrics(h.c.Cache))))
 	}
 }
 